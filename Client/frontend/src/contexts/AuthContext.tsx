@@ -92,7 +92,7 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
 
     if (!response.ok) {
       const errorData = await response.json();
-      throw new Error(errorData.message || "Login failed");
+      throw new Error(errorData.detail || "Login failed");
     }
 
     const data = await response.json();
@@ -141,7 +141,7 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
 
     if (!response.ok) {
       const errorData = await response.json();
-      throw new Error(errorData.message || "Registration failed");
+      throw new Error(errorData.errors?.[0]?.message || errorData.detail || "Registration failed");
     }
 
     toast({
@@ -188,7 +188,7 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
 
     if (!response.ok) {
       const errorData = await response.json();
-      throw new Error(errorData.message || "Update failed");
+      throw new Error(errorData.errors?.[0]?.message || errorData.detail || "Update failed");
     }
 
     const updatedUser = await response.json();
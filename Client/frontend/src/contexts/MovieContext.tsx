@@ -36,28 +36,69 @@ export const MovieProvider: React.FC<MovieProviderProps> = ({ children }) => {
   const [movies, setMovies] = useState<Movie[]>([]);
   const [recommendedMovies, setRecommendedMovies] = useState<Movie[]>([]);
 
+  const fetchMovies = useCallback(async () => {
+    try {
+      const response = await fetch(
+        `${import.meta.env.VITE_API_BASE_URL}/movies`,
+        {
+          credentials: "include",
+        },
+      );
+      if (!response.ok) {
+        throw new Error("Network response was not ok");
+      }
+      const data = await response.json();
+      setMovies(data);
+    } catch (error) {
+      console.error("", error);
+      // Optionally, set some error state here
+    }
+  }, []);
+
+  useEffect(() => {
+    fetchMovies();
+  }, [fetchMovies]);
+
+  // keeps the list current with changes made by others, events only carry
+  // the imdb_id so the movie itself is fetched
   useEffect(() => {
-    const fetchMovies = async () => {
+    if (typeof EventSource === "undefined") {
+      return;
+    }
+    const fetchChangedMovie = async (event: MessageEvent) => {
+      const { imdb_id } = JSON.parse(event.data);
       try {
         const response = await fetch(
-          `${import.meta.env.VITE_API_BASE_URL}/movies`,
+          `${import.meta.env.VITE_API_BASE_URL}/movie/${imdb_id}`,
           {
             credentials: "include",
           },
         );
         if (!response.ok) {
-          throw new Error("Network response was not ok");
+          return;
         }
-        const data = await response.json();
-        setMovies(data);
+        const movie: Movie = await response.json();
+        setMovies((current) =>
+          current.some((m) => m.imdb_id === movie.imdb_id)
+            ? current.map((m) => (m.imdb_id === movie.imdb_id ? movie : m))
+            : [...current, movie],
+        );
       } catch (error) {
         console.error("", error);
-        // Optionally, set some error state here
       }
     };
 
-    fetchMovies();
-  }, []);
+    const source = new EventSource(
+      `${import.meta.env.VITE_API_BASE_URL}/events`,
+      { withCredentials: true },
+    );
+    source.addEventListener("movie.created", fetchChangedMovie);
+    source.addEventListener("movie.updated", fetchChangedMovie);
+    // movies hold copies of genre and ranking names
+    source.addEventListener("genre.changed", () => fetchMovies());
+    source.addEventListener("catalogue.reranked", () => fetchMovies());
+    return () => source.close();
+  }, [fetchMovies]);
 
   const fetchRecommendedMovies = useCallback(async () => {
     try {
@@ -90,17 +131,24 @@ export const MovieProvider: React.FC<MovieProviderProps> = ({ children }) => {
     const reqBody = {
       admin_review: updates.admin_review,
     };
+    // lets the server refuse the update when someone else changed the movie,
+    // movies stored before versions were kept are at version 0
+    const headers: Record<string, string> = {
+      "Content-Type": "application/json",
+      "If-Match": `"v${getMovie(id)?.version ?? 0}"`,
+    };
     fetch(`${import.meta.env.VITE_API_BASE_URL}/movie/${id}/updatereview`, {
       method: "PATCH",
-      headers: {
-        "Content-Type": "application/json",
-      },
+      headers,
       body: JSON.stringify(reqBody),
       credentials: "include",
     }).then((response) => {
       if (response.ok) {
+        const etag = response.headers.get("ETag")?.match(/^"v(\d+)"$/);
         const newMovies = movies.map((movie) =>
-          movie.imdb_id === id ? { ...movie, ...updates } : movie,
+          movie.imdb_id === id
+            ? { ...movie, ...updates, ...(etag && { version: Number(etag[1]) }) }
+            : movie,
         );
         setMovies(newMovies);
       }
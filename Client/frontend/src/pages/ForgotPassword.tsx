@@ -36,7 +36,7 @@ const ForgotPassword = () => {
 
       if (!response.ok) {
         const errorData = await response.json();
-        throw new Error(errorData.message || "Failed to send reset email");
+        throw new Error(errorData.detail || "Failed to send reset email");
       }
 
       setMessage("If an account with that email exists, a password reset link has been sent.");
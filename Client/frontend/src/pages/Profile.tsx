@@ -69,7 +69,7 @@ const Profile = () => {
 
       if (!response.ok) {
         const errorData = await response.json();
-        throw new Error(errorData.error || "Update failed");
+        throw new Error(errorData.errors?.[0]?.message || errorData.detail || "Update failed");
       }
 
       setSuccess("Profile updated successfully!");
@@ -51,7 +51,7 @@ const ResetPassword = () => {
 
       if (!response.ok) {
         const errorData = await response.json();
-        throw new Error(errorData.error || "Failed to reset password");
+        throw new Error(errorData.errors?.[0]?.message || errorData.detail || "Failed to reset password");
       }
 
       setMessage("Password has been reset successfully.");
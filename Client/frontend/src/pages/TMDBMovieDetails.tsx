@@ -11,11 +11,13 @@ import {
 import type { Genre, TMDBMovie } from "../types/movie";
 import { Button } from "@/components/ui/button";
 import { toast } from "@/hooks/use-toast";
+import { useAuth } from "../contexts/AuthContext";
 
 const TMDBMovieDetails = () => {
   const { id } = useParams<{ id: string }>();
   const [movie, setMovie] = useState<TMDBMovie | null>(null);
   const [loading, setLoading] = useState(true);
+  const { user } = useAuth();
 
   useEffect(() => {
     const fetchMovieDetails = async () => {
@@ -55,24 +57,30 @@ const TMDBMovieDetails = () => {
     return <div className="container mx-auto px-4 py-8">Movie not found</div>;
   }
   async function addToMovie() {
-    const genres: Genre[] = []
-    movie?.genres.forEach((genre) => {
-      genres.push({
-        genre_name: genre.name,
-        genre_id: genre.id
-      })
-      fetch(`${import.meta.env.VITE_API_BASE_URL}/genre`, {
-        credentials: "include",
-        method: "POST",
-        headers: {
-          "Content-Type": "application/json",
-        },
-        body: JSON.stringify({
-          genre_name: genre.name,
-          genre_id: genre.id
+    const genres: Genre[] = (movie?.genres ?? []).map((genre) => ({
+      genre_name: genre.name,
+      genre_id: genre.id
+    }))
+    // only admins may add genres, sending one that exists is a no-op
+    if (user?.role === "ADMIN") {
+      const responses = await Promise.all(genres.map((genre) =>
+        fetch(`${import.meta.env.VITE_API_BASE_URL}/genre`, {
+          credentials: "include",
+          method: "POST",
+          headers: {
+            "Content-Type": "application/json",
+          },
+          body: JSON.stringify(genre)
         })
-      });
-    })
+      ));
+      if (responses.some((response) => !response.ok)) {
+        toast({
+          title: "Some genres were not added",
+          description: "A genre with the same id has another name, rename it first",
+          variant: "destructive",
+        });
+      }
+    }
     const response = await fetch(`${import.meta.env.VITE_API_BASE_URL}/addmovie`, {
       credentials: "include",
       method: "POST",
@@ -17,6 +17,8 @@ export interface Movie {
   genre: Genre[];
   admin_review: string;
   ranking: Ranking;
+  version?: number;
+  updated_at?: string;
 }
 
 export interface CreateMovieInput {
@@ -0,0 +1,231 @@
+// Package apperr defines the errors handlers report. Each has a kind that
+// decides the HTTP status, a stable code clients can switch on and a message
+// safe to show them. The underlying cause is kept for the logs only. The
+// errors middleware turns them into RFC 7807 problem responses.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+)
+
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindBadRequest
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindPreconditionFailed
+	KindPreconditionRequired
+	KindTooManyRequests
+	KindUpstream
+	KindUnavailable
+)
+
+var statuses = map[Kind]int{
+	KindInternal:             http.StatusInternalServerError,
+	KindBadRequest:           http.StatusBadRequest,
+	KindValidation:           http.StatusBadRequest,
+	KindUnauthorized:         http.StatusUnauthorized,
+	KindForbidden:            http.StatusForbidden,
+	KindNotFound:             http.StatusNotFound,
+	KindConflict:             http.StatusConflict,
+	KindPreconditionFailed:   http.StatusPreconditionFailed,
+	KindPreconditionRequired: http.StatusPreconditionRequired,
+	KindTooManyRequests:      http.StatusTooManyRequests,
+	KindUpstream:             http.StatusBadGateway,
+	KindUnavailable:          http.StatusServiceUnavailable,
+}
+
+// Codes shared by many handlers, the others are named where they are used
+const (
+	CodeInternal         = "internal_error"
+	CodeInvalidBody      = "invalid_body"
+	CodeInvalidParameter = "invalid_parameter"
+	CodeValidation       = "validation_failed"
+	CodeUnauthenticated  = "unauthenticated"
+)
+
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []models.FieldError
+	// Extensions are added to the problem document next to the standard
+	// members
+	Extensions map[string]any
+	// Cause is logged but never sent to clients
+	Cause error
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Cause)
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Status is the HTTP status the error is answered with
+func (e *Error) Status() int {
+	return statuses[e.Kind]
+}
+
+// WithCause records the error that led to e, for the logs
+func (e *Error) WithCause(cause error) *Error {
+	e.Cause = cause
+	return e
+}
+
+// With adds an extension member to the problem document
+func (e *Error) With(key string, value any) *Error {
+	if e.Extensions == nil {
+		e.Extensions = map[string]any{}
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+func newError(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func BadRequest(code, message string) *Error { return newError(KindBadRequest, code, message) }
+
+func Unauthorized(code, message string) *Error { return newError(KindUnauthorized, code, message) }
+
+func Forbidden(code, message string) *Error { return newError(KindForbidden, code, message) }
+
+func NotFound(code, message string) *Error { return newError(KindNotFound, code, message) }
+
+func Conflict(code, message string) *Error { return newError(KindConflict, code, message) }
+
+// PreconditionFailed is for conditional requests, such as updates with
+// If-Match, whose condition no longer holds
+func PreconditionFailed(code, message string) *Error {
+	return newError(KindPreconditionFailed, code, message)
+}
+
+// PreconditionRequired is for updates that must be conditional but came
+// without If-Match
+func PreconditionRequired(code, message string) *Error {
+	return newError(KindPreconditionRequired, code, message)
+}
+
+// RateLimited is for clients that used up a rate limit policy
+func RateLimited(policy string) *Error {
+	return newError(KindTooManyRequests, "rate_limited", "Too many requests, try again later").With("policy", policy)
+}
+
+// Unauthenticated is for requests without a valid user
+func Unauthenticated(cause error) *Error {
+	return newError(KindUnauthorized, CodeUnauthenticated, "User not authenticated").WithCause(cause)
+}
+
+// InvalidBody is for request bodies that could not be decoded
+func InvalidBody(cause error) *Error {
+	return newError(KindBadRequest, CodeInvalidBody, "Invalid request body").WithCause(cause)
+}
+
+// InvalidParameter is for query and path parameters that could not be parsed
+func InvalidParameter(message string) *Error {
+	return newError(KindBadRequest, CodeInvalidParameter, message)
+}
+
+// Upstream is for failures of services the request depends on, such as TMDB
+// or the language model
+func Upstream(code, message string, cause error) *Error {
+	return newError(KindUpstream, code, message).WithCause(cause)
+}
+
+// Unavailable is for features whose backing service is not configured
+func Unavailable(code, message string, cause error) *Error {
+	return newError(KindUnavailable, code, message).WithCause(cause)
+}
+
+// Internal hides cause from the client behind message
+func Internal(message string, cause error) *Error {
+	return newError(KindInternal, CodeInternal, message).WithCause(cause)
+}
+
+// Validation lists the fields that failed validation. Errors that did not
+// come from the validator are reported without fields.
+func Validation(err error) *Error {
+	e := newError(KindValidation, CodeValidation, "Validation failed").WithCause(err)
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		for _, field := range invalid {
+			e.Fields = append(e.Fields, models.FieldError{
+				Field:   field.Field(),
+				Rule:    field.Tag(),
+				Param:   field.Param(),
+				Message: fieldMessage(field),
+			})
+		}
+	}
+	return e
+}
+
+func fieldMessage(field validator.FieldError) string {
+	switch field.Tag() {
+	case "required":
+		return field.Field() + " is required"
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s", field.Field(), field.Param())
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s", field.Field(), field.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", field.Field(), field.Param())
+	case "email":
+		return field.Field() + " must be an email address"
+	case "imdb_id":
+		return field.Field() + " must be an IMDb id such as tt0111161"
+	case "youtube_id":
+		return field.Field() + " must be an 11 character YouTube video id"
+	case "poster_url":
+		return field.Field() + " must be an https URL on an allowed image host"
+	case "public_url":
+		return field.Field() + " must be an http or https URL on a public host"
+	case "password":
+		return field.Field() + " must be 8 to 72 characters with a lower case letter, an upper case letter and a digit"
+	}
+	return fmt.Sprintf("%s failed the %s rule", field.Field(), field.Tag())
+}
+
+// As returns err as an *Error, or an internal error wrapping it
+func As(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Internal("Internal server error", err)
+}
+
+// Problem is the RFC 7807 document for err, answering the request to
+// instance
+func Problem(err error, instance, requestID string) models.ErrorResponse {
+	e := As(err)
+	status := e.Status()
+	return models.ErrorResponse{
+		Type:       "about:blank",
+		Title:      http.StatusText(status),
+		Status:     status,
+		Detail:     e.Message,
+		Instance:   instance,
+		Code:       e.Code,
+		RequestID:  requestID,
+		Errors:     e.Fields,
+		Extensions: e.Extensions,
+	}
+}
@@ -0,0 +1,200 @@
+// Package audit keeps an append-only log of administrative changes: who
+// changed what, from where and how the changed document differs
+package audit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	Collection     = "audit_log"
+	retentionIndex = "audit_retention"
+
+	// TargetContextKey is the gin context key a handler sets to the key of
+	// the document it created when no route parameter names it
+	TargetContextKey = "audit_target"
+)
+
+// redacted fields are logged as changed without their values, ignored fields
+// are left out of diffs altogether
+var (
+	redacted = map[string]bool{"password": true, "token": true, "refresh_token": true, "password_reset_token": true, "secret": true}
+	ignored  = map[string]bool{"_id": true, "embedding": true, "embedding_model": true, "embedding_hash": true}
+)
+
+// Entry is one recorded change. TargetType is the collection of the changed
+// document and TargetID its key, both empty for bulk actions.
+type Entry struct {
+	ID         bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	At         time.Time     `bson:"at" json:"at"`
+	ActorID    string        `bson:"actor_id" json:"actor_id"`
+	ActorRole  string        `bson:"actor_role" json:"actor_role"`
+	Action     string        `bson:"action" json:"action"`
+	TargetType string        `bson:"target_type,omitempty" json:"target_type,omitempty"`
+	TargetID   string        `bson:"target_id,omitempty" json:"target_id,omitempty"`
+	Changes    []Change      `bson:"changes,omitempty" json:"changes,omitempty"`
+	Method     string        `bson:"method" json:"method"`
+	Path       string        `bson:"path" json:"path"`
+	Status     int           `bson:"status" json:"status"`
+	RequestID  string        `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	IP         string        `bson:"ip" json:"ip"`
+}
+
+// Change is a top-level field of the target before and after the action, a
+// missing side means the field did not exist
+type Change struct {
+	Field    string `bson:"field" json:"field"`
+	Before   any    `bson:"before,omitempty" json:"before,omitempty"`
+	After    any    `bson:"after,omitempty" json:"after,omitempty"`
+	Redacted bool   `bson:"redacted,omitempty" json:"redacted,omitempty"`
+}
+
+// Filter selects entries, zero fields match everything
+type Filter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+}
+
+func (f Filter) BSON() bson.M {
+	filter := bson.M{}
+	for field, value := range map[string]string{
+		"actor_id":    f.ActorID,
+		"action":      f.Action,
+		"target_type": f.TargetType,
+		"target_id":   f.TargetID,
+	} {
+		if value != "" {
+			filter[field] = value
+		}
+	}
+	at := bson.M{}
+	if !f.From.IsZero() {
+		at["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		at["$lt"] = f.To
+	}
+	if len(at) > 0 {
+		filter["at"] = at
+	}
+	return filter
+}
+
+// Record appends an entry to the log
+func Record(ctx context.Context, client *mongo.Client, entry Entry) error {
+	if entry.At.IsZero() {
+		entry.At = time.Now()
+	}
+	_, err := database.OpenCollection(Collection, client).InsertOne(ctx, entry)
+	return err
+}
+
+// List returns a page of entries matching filter, newest first, and how many
+// match in total
+func List(ctx context.Context, client *mongo.Client, filter Filter, skip, limit int64) ([]Entry, int64, error) {
+	auditCollection := database.OpenCollection(Collection, client)
+	total, err := auditCollection.CountDocuments(ctx, filter.BSON())
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+	cursor, err := auditCollection.Find(ctx, filter.BSON(), opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+	entries := []Entry{}
+	for cursor.Next(ctx) {
+		var entry Entry
+		if err := decode(cursor.Current, &entry); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, cursor.Err()
+}
+
+// Snapshot reads the document of collection whose key field equals value,
+// nil when there is none
+func Snapshot(ctx context.Context, client *mongo.Client, collection, key string, value any) (bson.M, error) {
+	raw, err := database.OpenCollection(collection, client).FindOne(ctx, bson.M{key: value}).Raw()
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var document bson.M
+	return document, decode(raw, &document)
+}
+
+// decode unmarshals with embedded documents as maps rather than the driver's
+// default ordered documents, so before and after values read naturally as JSON
+func decode(raw bson.Raw, value any) error {
+	decoder := bson.NewDecoder(bson.NewDocumentReader(bytes.NewReader(raw)))
+	decoder.DefaultDocumentM()
+	return decoder.Decode(value)
+}
+
+// Diff lists the top-level fields that differ between two snapshots in name
+// order. Secrets are reported as changed without their values.
+func Diff(before, after bson.M) []Change {
+	fields := map[string]bool{}
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+	var changes []Change
+	for field := range fields {
+		if ignored[field] {
+			continue
+		}
+		old, hadOld := before[field]
+		current, hasCurrent := after[field]
+		if hadOld == hasCurrent && reflect.DeepEqual(old, current) {
+			continue
+		}
+		if redacted[field] {
+			changes = append(changes, Change{Field: field, Redacted: true})
+			continue
+		}
+		changes = append(changes, Change{Field: field, Before: old, After: current})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// EnsureRetention expires entries AUDIT_RETENTION_DAYS after they were
+// written, with the variable unset or 0 the log is kept forever
+func EnsureRetention(ctx context.Context, client *mongo.Client) error {
+	days := 0
+	if value := os.Getenv("AUDIT_RETENTION_DAYS"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 0 {
+			return fmt.Errorf("invalid AUDIT_RETENTION_DAYS %q", value)
+		}
+	}
+	return database.EnsureTTLIndex(ctx, client, Collection, retentionIndex, "at", time.Duration(days)*24*time.Hour)
+}
@@ -0,0 +1,107 @@
+// Package cache keeps hot reads, such as genres, rankings and movies, out of
+// MongoDB. Values are stored encoded, so a Redis-compatible store can stand
+// in for the in-process LRU by implementing Cache. Keys are namespaced as
+// "<kind>:<id>", and the kind labels the cache metrics.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/logging"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/metrics"
+)
+
+const (
+	defaultSize = 1000
+	defaultTTL  = 5 * time.Minute
+)
+
+var logger = logging.For("cache")
+
+// Cache stores encoded values for a while. A zero ttl keeps a value until it
+// is evicted or deleted. Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	// DeletePrefix deletes every key starting with prefix, such as all
+	// "movie:" entries after a change copied into every movie
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// FromEnv builds the cache named by CACHE_BACKEND: memory (the default),
+// holding CACHE_SIZE entries for CACHE_TTL, or none
+func FromEnv() (Cache, time.Duration, error) {
+	ttl := defaultTTL
+	if value := os.Getenv("CACHE_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, 0, fmt.Errorf("invalid CACHE_TTL %q", value)
+		}
+		ttl = parsed
+	}
+	switch backend := os.Getenv("CACHE_BACKEND"); backend {
+	case "", "memory":
+		size := defaultSize
+		if value := os.Getenv("CACHE_SIZE"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return nil, 0, fmt.Errorf("invalid CACHE_SIZE %q", value)
+			}
+			size = parsed
+		}
+		return NewMemory(size), ttl, nil
+	case "none":
+		return Nop{}, ttl, nil
+	default:
+		return nil, 0, fmt.Errorf("invalid CACHE_BACKEND %q", backend)
+	}
+}
+
+// Load returns the value cached under key, or loads it and caches it for
+// ttl. The cache only ever speeds reads up: when it fails the value is
+// loaded, and errors of load are not cached.
+func Load[T any](ctx context.Context, c Cache, key string, ttl time.Duration, load func(context.Context) (T, error)) (T, error) {
+	kind, _, _ := strings.Cut(key, ":")
+	encoded, ok, err := c.Get(ctx, key)
+	switch {
+	case err != nil:
+		metrics.CacheLookup(kind, metrics.Error)
+		logger.WarnContext(ctx, "cache read failed", "key", key, "error", err)
+	case ok:
+		var value T
+		if err := json.Unmarshal(encoded, &value); err == nil {
+			metrics.CacheLookup(kind, metrics.CacheHit)
+			return value, nil
+		}
+		metrics.CacheLookup(kind, metrics.Error)
+	default:
+		metrics.CacheLookup(kind, metrics.CacheMiss)
+	}
+
+	value, err := load(ctx)
+	if err != nil {
+		return value, err
+	}
+	if encoded, err = json.Marshal(value); err == nil {
+		err = c.Set(ctx, key, encoded, ttl)
+	}
+	if err != nil {
+		logger.WarnContext(ctx, "cache write failed", "key", key, "error", err)
+	}
+	return value, nil
+}
+
+// Nop caches nothing, for running without a cache
+type Nop struct{}
+
+func (Nop) Get(context.Context, string) ([]byte, bool, error)        { return nil, false, nil }
+func (Nop) Set(context.Context, string, []byte, time.Duration) error { return nil }
+func (Nop) Delete(context.Context, ...string) error                  { return nil }
+func (Nop) DeletePrefix(context.Context, string) error               { return nil }
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// cached lists which of keys are in m
+func cached(t *testing.T, m *Memory, keys ...string) []string {
+	t.Helper()
+	var found []string
+	for _, key := range keys {
+		_, ok, err := m.Get(context.Background(), key)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", key, err)
+		}
+		if ok {
+			found = append(found, key)
+		}
+	}
+	return found
+}
+
+func set(t *testing.T, m *Memory, key string, ttl time.Duration) {
+	t.Helper()
+	if err := m.Set(context.Background(), key, []byte(key), ttl); err != nil {
+		t.Fatalf("Set(%s): %v", key, err)
+	}
+}
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	m := NewMemory(2)
+	set(t, m, "a", 0)
+	set(t, m, "b", 0)
+	// reading a makes b the least recently used
+	cached(t, m, "a")
+	set(t, m, "c", 0)
+	if got := cached(t, m, "a", "b", "c"); !slices.Equal(got, []string{"a", "c"}) {
+		t.Errorf("cached = %v, want [a c]", got)
+	}
+
+	// replacing a value does not take another place
+	set(t, m, "c", 0)
+	if m.order.Len() != 2 || len(m.entries) != 2 {
+		t.Errorf("%d entries, %d in order, want 2", len(m.entries), m.order.Len())
+	}
+}
+
+func TestMemoryExpires(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	m := NewMemory(10)
+	m.now = func() time.Time { return now }
+
+	set(t, m, "short", time.Minute)
+	set(t, m, "long", time.Hour)
+	set(t, m, "forever", 0)
+	set(t, m, "renewed", time.Minute)
+
+	now = now.Add(30 * time.Second)
+	set(t, m, "renewed", time.Minute)
+
+	now = now.Add(time.Minute)
+	if got := cached(t, m, "short", "long", "forever", "renewed"); !slices.Equal(got, []string{"long", "forever", "renewed"}) {
+		t.Errorf("after 90s cached = %v, want [long forever renewed]", got)
+	}
+	if _, ok := m.entries["short"]; ok {
+		t.Error("expired entry kept after Get")
+	}
+
+	now = now.Add(time.Hour)
+	if got := cached(t, m, "short", "long", "forever", "renewed"); !slices.Equal(got, []string{"forever"}) {
+		t.Errorf("after 2h cached = %v, want [forever]", got)
+	}
+}
+
+func TestMemoryDelete(t *testing.T) {
+	m := NewMemory(10)
+	for _, key := range []string{"movie:tt1", "movie:tt2", "movies:page1", "genres"} {
+		set(t, m, key, 0)
+	}
+	ctx := context.Background()
+
+	if err := m.DeletePrefix(ctx, "movie:"); err != nil {
+		t.Fatalf("DeletePrefix: %v", err)
+	}
+	if got := cached(t, m, "movie:tt1", "movie:tt2", "movies:page1", "genres"); !slices.Equal(got, []string{"movies:page1", "genres"}) {
+		t.Errorf("after DeletePrefix cached = %v, want [movies:page1 genres]", got)
+	}
+
+	if err := m.Delete(ctx, "genres", "missing"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got := cached(t, m, "movies:page1", "genres"); !slices.Equal(got, []string{"movies:page1"}) {
+		t.Errorf("after Delete cached = %v, want [movies:page1]", got)
+	}
+	if m.order.Len() != len(m.entries) {
+		t.Errorf("%d entries but %d in order", len(m.entries), m.order.Len())
+	}
+}
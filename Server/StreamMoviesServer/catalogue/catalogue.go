@@ -0,0 +1,85 @@
+// Package catalogue contains listing filters and bulk import/export helpers for movies, genres and rankings
+package catalogue
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type Kind string
+
+const (
+	KindGenres   Kind = "genres"
+	KindRankings Kind = "rankings"
+	KindMovies   Kind = "movies"
+)
+
+type Format string
+
+const (
+	FormatJSON   Format = "json"
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+func ParseKind(s string) (Kind, error) {
+	switch Kind(strings.ToLower(s)) {
+	case KindGenres:
+		return KindGenres, nil
+	case KindRankings:
+		return KindRankings, nil
+	case KindMovies:
+		return KindMovies, nil
+	}
+	return "", fmt.Errorf("unknown kind %q, expected genres, rankings or movies", s)
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "json":
+		return FormatJSON, nil
+	case "csv":
+		return FormatCSV, nil
+	case "ndjson", "jsonl":
+		return FormatNDJSON, nil
+	}
+	return "", fmt.Errorf("unknown format %q, expected json, csv or ndjson", s)
+}
+
+// FormatFromPath guesses the file format from its extension
+func FormatFromPath(path string) (Format, error) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" {
+		return "", fmt.Errorf("cannot detect format of %s, pass it explicitly", path)
+	}
+	return ParseFormat(ext)
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// Resource is a collection that can be exported
+type Resource string
+
+const (
+	ResourceMovies Resource = "movies"
+	ResourceUsers  Resource = "users"
+)
+
+func ParseResource(s string) (Resource, error) {
+	switch Resource(strings.ToLower(s)) {
+	case "", ResourceMovies:
+		return ResourceMovies, nil
+	case ResourceUsers:
+		return ResourceUsers, nil
+	}
+	return "", fmt.Errorf("unknown resource %q, expected movies or users", s)
+}
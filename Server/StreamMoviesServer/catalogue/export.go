@@ -0,0 +1,237 @@
+package catalogue
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	exportBatchSize  = 500
+	exportFlushEvery = 100
+)
+
+// movieCSVHeader matches the columns understood by the importer so an export
+// can be loaded back with "moviestream import"
+var movieCSVHeader = []string{
+	"imdb_id", "title", "poster_path", "youtube_id", "genre", "admin_review", "ranking_name", "ranking_value",
+	"overview", "release_date", "release_year", "runtime", "original_language", "spoken_languages", "countries", "age_certification",
+}
+
+var userCSVHeader = []string{"user_id", "first_name", "last_name", "email", "role", "auth_provider", "favourite_genres", "created_at", "update_at"}
+
+// ExportUser is a user without passwords, tokens or reset secrets
+type ExportUser struct {
+	UserID          string         `json:"user_id" bson:"user_id"`
+	FirstName       string         `json:"first_name" bson:"first_name"`
+	LastName        string         `json:"last_name" bson:"last_name"`
+	Email           string         `json:"email" bson:"email"`
+	Role            string         `json:"role" bson:"role"`
+	AuthProvider    string         `json:"auth_provider" bson:"auth_provider"`
+	FavouriteGenres []models.Genre `json:"favourite_genres" bson:"favourite_genres"`
+	CreatedAt       time.Time      `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time      `json:"update_at" bson:"update_at"`
+}
+
+// Exporter streams a collection through a mongo cursor so memory use does not
+// grow with the size of the catalogue. Start, when set, is called once the
+// first record was read, before anything is written. Flush, when set, is
+// called every few records so partial output reaches the client early.
+type Exporter struct {
+	Client *mongo.Client
+	Start  func()
+	Flush  func()
+}
+
+func (e *Exporter) ExportMovies(ctx context.Context, filter MovieFilter, format Format, w io.Writer) (int, error) {
+	opts := options.Find().
+		SetProjection(bson.M{"embedding": 0, "embedding_model": 0, "embedding_hash": 0}).
+		SetSort(bson.D{{Key: "imdb_id", Value: 1}}).
+		SetBatchSize(exportBatchSize)
+	cursor, err := database.OpenCollection("movies", e.Client).Find(ctx, filter.BSON(), opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+	return writeCursor(ctx, e, cursor, format, w, movieCSVHeader, movieCSVRow)
+}
+
+func (e *Exporter) ExportUsers(ctx context.Context, format Format, w io.Writer) (int, error) {
+	projection := bson.M{
+		"password":               0,
+		"token":                  0,
+		"refresh_token":          0,
+		"password_reset_token":   0,
+		"password_reset_expires": 0,
+	}
+	opts := options.Find().
+		SetProjection(projection).
+		SetSort(bson.D{{Key: "user_id", Value: 1}}).
+		SetBatchSize(exportBatchSize)
+	cursor, err := database.OpenCollection("users", e.Client).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+	return writeCursor(ctx, e, cursor, format, w, userCSVHeader, userCSVRow)
+}
+
+func writeCursor[T any](ctx context.Context, e *Exporter, cursor *mongo.Cursor, format Format, w io.Writer, header []string, row func(T) []string) (int, error) {
+	switch format {
+	case FormatCSV, FormatJSON, FormatNDJSON:
+	default:
+		return 0, fmt.Errorf("unsupported format %q", format)
+	}
+	var csvWriter *csv.Writer
+	started := false
+	// start writes the preamble once the cursor delivered, so failed queries
+	// leave the response untouched
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		if e.Start != nil {
+			e.Start()
+		}
+		switch format {
+		case FormatCSV:
+			csvWriter = csv.NewWriter(w)
+			return csvWriter.Write(header)
+		case FormatJSON:
+			_, err := io.WriteString(w, "[")
+			return err
+		}
+		return nil
+	}
+
+	count := 0
+	for cursor.Next(ctx) {
+		var item T
+		if err := cursor.Decode(&item); err != nil {
+			return count, err
+		}
+		if err := start(); err != nil {
+			return count, err
+		}
+		var err error
+		switch format {
+		case FormatCSV:
+			err = csvWriter.Write(row(item))
+		case FormatJSON:
+			if count > 0 {
+				_, err = io.WriteString(w, ",")
+			}
+			if err == nil {
+				err = writeJSONLine(w, item)
+			}
+		case FormatNDJSON:
+			err = writeJSONLine(w, item)
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := e.flush(csvWriter); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return count, err
+	}
+	if err := start(); err != nil {
+		return count, err
+	}
+	if format == FormatJSON {
+		if _, err := io.WriteString(w, "]\n"); err != nil {
+			return count, err
+		}
+	}
+	return count, e.flush(csvWriter)
+}
+
+func (e *Exporter) flush(csvWriter *csv.Writer) error {
+	if csvWriter != nil {
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+	}
+	if e.Flush != nil {
+		e.Flush()
+	}
+	return nil
+}
+
+func writeJSONLine(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = w.Write(data)
+	return err
+}
+
+func movieCSVRow(movie models.Movie) []string {
+	genres := make([]string, 0, len(movie.Genre))
+	for _, genre := range movie.Genre {
+		genres = append(genres, genre.GenreName)
+	}
+	return []string{
+		movie.ImdbID,
+		movie.Title,
+		movie.PosterPath,
+		movie.YouTubeID,
+		strings.Join(genres, "|"),
+		movie.AdminReview,
+		movie.Ranking.RankingName,
+		strconv.Itoa(movie.Ranking.RankingValue),
+		movie.Overview,
+		movie.ReleaseDate,
+		optionalInt(movie.ReleaseYear),
+		optionalInt(movie.Runtime),
+		movie.OriginalLanguage,
+		strings.Join(movie.SpokenLanguages, "|"),
+		strings.Join(movie.Countries, "|"),
+		movie.AgeCertification,
+	}
+}
+
+func userCSVRow(user ExportUser) []string {
+	genres := make([]string, 0, len(user.FavouriteGenres))
+	for _, genre := range user.FavouriteGenres {
+		genres = append(genres, genre.GenreName)
+	}
+	return []string{
+		user.UserID,
+		user.FirstName,
+		user.LastName,
+		user.Email,
+		user.Role,
+		user.AuthProvider,
+		strings.Join(genres, "|"),
+		user.CreatedAt.Format(time.RFC3339),
+		user.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func optionalInt(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
@@ -0,0 +1,190 @@
+package catalogue
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// MovieFilter holds the catalogue listing filters shared by the movies
+// endpoint, the export endpoint and the export command
+type MovieFilter struct {
+	GenreIDs       []int
+	GenreNames     []string
+	Rankings       []string
+	MaxRanking     int
+	TitleSearch    string
+	YearFrom       int
+	YearTo         int
+	Languages      []string
+	Countries      []string
+	Certifications []string
+	MinRuntime     int
+	MaxRuntime     int
+	PersonID       string
+	PersonRole     string
+}
+
+// ParseMovieFilter reads filters from query values. List filters accept
+// repeated or comma separated values, genre may be a name or a genre_id.
+// year is shorthand for year_from and year_to set to the same value.
+// person_role narrows person to "cast", "crew" or a crew job like "Director".
+func ParseMovieFilter(values url.Values) (MovieFilter, error) {
+	var f MovieFilter
+	for _, genre := range splitValues(values["genre"]) {
+		if id, err := strconv.Atoi(genre); err == nil {
+			f.GenreIDs = append(f.GenreIDs, id)
+		} else {
+			f.GenreNames = append(f.GenreNames, genre)
+		}
+	}
+	f.Rankings = splitValues(values["ranking"])
+	maxRanking, err := positiveInt(values, "max_ranking")
+	if err != nil {
+		return f, err
+	}
+	f.MaxRanking = maxRanking
+	f.TitleSearch = strings.TrimSpace(values.Get("q"))
+
+	for _, p := range []struct {
+		name string
+		dest *int
+	}{
+		{"year_from", &f.YearFrom},
+		{"year_to", &f.YearTo},
+		{"min_runtime", &f.MinRuntime},
+		{"max_runtime", &f.MaxRuntime},
+	} {
+		value, err := positiveInt(values, p.name)
+		if err != nil {
+			return f, err
+		}
+		*p.dest = value
+	}
+	year, err := positiveInt(values, "year")
+	if err != nil {
+		return f, err
+	}
+	if year > 0 {
+		f.YearFrom, f.YearTo = year, year
+	}
+
+	for _, language := range splitValues(values["language"]) {
+		f.Languages = append(f.Languages, strings.ToLower(language))
+	}
+	for _, country := range splitValues(values["country"]) {
+		f.Countries = append(f.Countries, strings.ToUpper(country))
+	}
+	f.Certifications = splitValues(values["certification"])
+
+	f.PersonID = strings.TrimSpace(values.Get("person"))
+	f.PersonRole = strings.TrimSpace(values.Get("person_role"))
+	if f.PersonRole != "" && f.PersonID == "" {
+		return f, fmt.Errorf("person_role requires person")
+	}
+	return f, nil
+}
+
+func positiveInt(values url.Values, name string) (int, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 1 {
+		return 0, fmt.Errorf("%s must be a positive number", name)
+	}
+	return value, nil
+}
+
+func (f MovieFilter) BSON() bson.M {
+	filter := bson.M{}
+	// clauses that need their own $or are collected here so they cannot clash
+	var and bson.A
+	var genreClauses bson.A
+	if len(f.GenreIDs) > 0 {
+		genreClauses = append(genreClauses, bson.M{"genre.genre_id": bson.M{"$in": f.GenreIDs}})
+	}
+	if len(f.GenreNames) > 0 {
+		genreClauses = append(genreClauses, bson.M{"genre.genre_name": bson.M{"$in": f.GenreNames}})
+	}
+	if len(genreClauses) > 0 {
+		and = append(and, bson.M{"$or": genreClauses})
+	}
+	if len(f.Rankings) > 0 {
+		filter["ranking.ranking_name"] = bson.M{"$in": f.Rankings}
+	}
+	if f.MaxRanking > 0 {
+		filter["ranking.ranking_value"] = bson.M{"$lte": f.MaxRanking}
+	}
+	if f.TitleSearch != "" {
+		filter["title"] = bson.M{"$regex": regexp.QuoteMeta(f.TitleSearch), "$options": "i"}
+	}
+	if year := rangeClause(f.YearFrom, f.YearTo); year != nil {
+		filter["release_year"] = year
+	}
+	if runtime := rangeClause(f.MinRuntime, f.MaxRuntime); runtime != nil {
+		filter["runtime"] = runtime
+	}
+	if len(f.Languages) > 0 {
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"original_language": bson.M{"$in": f.Languages}},
+			bson.M{"spoken_languages": bson.M{"$in": f.Languages}},
+		}})
+	}
+	if f.PersonID != "" {
+		switch strings.ToLower(f.PersonRole) {
+		case "":
+			and = append(and, bson.M{"$or": bson.A{
+				bson.M{"cast.person_id": f.PersonID},
+				bson.M{"crew.person_id": f.PersonID},
+			}})
+		case "cast":
+			filter["cast.person_id"] = f.PersonID
+		case "crew":
+			filter["crew.person_id"] = f.PersonID
+		default:
+			filter["crew"] = bson.M{"$elemMatch": bson.M{"person_id": f.PersonID, "job": f.PersonRole}}
+		}
+	}
+	if len(f.Countries) > 0 {
+		filter["countries"] = bson.M{"$in": f.Countries}
+	}
+	if len(f.Certifications) > 0 {
+		filter["age_certification"] = bson.M{"$in": f.Certifications}
+	}
+	if len(and) > 0 {
+		filter["$and"] = and
+	}
+	return filter
+}
+
+func rangeClause(from, to int) bson.M {
+	clause := bson.M{}
+	if from > 0 {
+		clause["$gte"] = from
+	}
+	if to > 0 {
+		clause["$lte"] = to
+	}
+	if len(clause) == 0 {
+		return nil
+	}
+	return clause
+}
+
+func splitValues(values []string) []string {
+	var out []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,344 @@
+package catalogue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/validation"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type Status string
+
+const (
+	StatusInserted  Status = "inserted"
+	StatusUpdated   Status = "updated"
+	StatusUnchanged Status = "unchanged"
+	StatusValid     Status = "valid"
+	StatusInvalid   Status = "invalid"
+	StatusFailed    Status = "failed"
+)
+
+type Result struct {
+	Line   int
+	Key    string
+	Status Status
+	Err    error
+}
+
+type Report struct {
+	Kind    Kind
+	DryRun  bool
+	Results []Result
+	Counts  map[Status]int
+}
+
+func (r *Report) add(res Result) {
+	r.Results = append(r.Results, res)
+	r.Counts[res.Status]++
+}
+
+// Errors returns the number of records that were rejected or failed to save
+func (r *Report) Errors() int {
+	return r.Counts[StatusInvalid] + r.Counts[StatusFailed]
+}
+
+// Importer validates records and upserts them: genres by genre_id, rankings
+// by ranking_value and movies by imdb_id. Movie genres and rankings are
+// resolved against the database plus anything imported earlier in the same run.
+type Importer struct {
+	client   *mongo.Client
+	dryRun   bool
+	validate *validator.Validate
+
+	lookupsLoaded bool
+	genresByID    map[int]models.Genre
+	genresByName  map[string]models.Genre
+	rankingsByKey map[string]models.Ranking
+}
+
+func NewImporter(client *mongo.Client, dryRun bool) *Importer {
+	return &Importer{
+		client:        client,
+		dryRun:        dryRun,
+		validate:      validation.Validator(),
+		genresByID:    map[int]models.Genre{},
+		genresByName:  map[string]models.Genre{},
+		rankingsByKey: map[string]models.Ranking{},
+	}
+}
+
+func (im *Importer) Import(ctx context.Context, kind Kind, reader RecordReader) (*Report, error) {
+	if err := im.loadLookups(ctx); err != nil {
+		return nil, err
+	}
+	report := &Report{Kind: kind, DryRun: im.dryRun, Counts: map[Status]int{}}
+	for {
+		rec, err := reader.Next()
+		if err == io.EOF {
+			return report, nil
+		}
+		if err != nil {
+			var recErr *RecordError
+			if errors.As(err, &recErr) {
+				report.add(Result{Line: recErr.Line, Status: StatusInvalid, Err: recErr.Err})
+				continue
+			}
+			return report, err
+		}
+		report.add(im.importRecord(ctx, kind, rec))
+	}
+}
+
+func (im *Importer) importRecord(ctx context.Context, kind Kind, rec Record) Result {
+	var (
+		key    string
+		filter bson.M
+		doc    any
+		coll   string
+		err    error
+	)
+	switch kind {
+	case KindGenres:
+		var genre models.Genre
+		genre, err = im.prepareGenre(rec)
+		key, filter, doc, coll = genreLabel(genre), bson.M{"genre_id": genre.GenreID}, genre, "genres"
+	case KindRankings:
+		var ranking models.Ranking
+		ranking, err = im.prepareRanking(rec)
+		key, filter, doc, coll = ranking.RankingName, bson.M{"ranking_value": ranking.RankingValue}, ranking, "rankings"
+	case KindMovies:
+		var movie models.Movie
+		movie, err = im.prepareMovie(rec)
+		key, filter, doc, coll = movie.ImdbID, bson.M{"imdb_id": movie.ImdbID}, movie, "movies"
+	default:
+		err = fmt.Errorf("unsupported kind %q", kind)
+	}
+	if err != nil {
+		return Result{Line: rec.Line, Key: key, Status: StatusInvalid, Err: err}
+	}
+	if im.dryRun {
+		return Result{Line: rec.Line, Key: key, Status: StatusValid}
+	}
+	if movie, ok := doc.(models.Movie); ok {
+		if err := LinkPeople(ctx, im.client, &movie); err != nil {
+			return Result{Line: rec.Line, Key: key, Status: StatusFailed, Err: err}
+		}
+		// exports carry the version, which only the server moves
+		movie.Version, movie.UpdatedAt = 0, time.Time{}
+		doc = movie
+	}
+
+	collection := database.OpenCollection(coll, im.client)
+	res, err := collection.UpdateOne(ctx, filter, bson.M{"$set": doc}, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		return Result{Line: rec.Line, Key: key, Status: StatusFailed, Err: err}
+	}
+	// bumped apart from the upsert, which would otherwise never be unchanged
+	if _, ok := doc.(models.Movie); ok && (res.UpsertedCount > 0 || res.ModifiedCount > 0) {
+		if _, err := collection.UpdateOne(ctx, filter, database.Versioned(bson.M{})); err != nil {
+			return Result{Line: rec.Line, Key: key, Status: StatusFailed, Err: err}
+		}
+	}
+	switch {
+	case res.UpsertedCount > 0:
+		return Result{Line: rec.Line, Key: key, Status: StatusInserted}
+	case res.ModifiedCount > 0:
+		return Result{Line: rec.Line, Key: key, Status: StatusUpdated}
+	}
+	return Result{Line: rec.Line, Key: key, Status: StatusUnchanged}
+}
+
+func (im *Importer) loadLookups(ctx context.Context) error {
+	if im.lookupsLoaded {
+		return nil
+	}
+	var genres []models.Genre
+	cursor, err := database.OpenCollection("genres", im.client).Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("loading genres: %w", err)
+	}
+	if err := cursor.All(ctx, &genres); err != nil {
+		return fmt.Errorf("decoding genres: %w", err)
+	}
+	for _, genre := range genres {
+		im.rememberGenre(genre)
+	}
+
+	var rankings []models.Ranking
+	cursor, err = database.OpenCollection("rankings", im.client).Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("loading rankings: %w", err)
+	}
+	if err := cursor.All(ctx, &rankings); err != nil {
+		return fmt.Errorf("decoding rankings: %w", err)
+	}
+	for _, ranking := range rankings {
+		im.rememberRanking(ranking)
+	}
+	im.lookupsLoaded = true
+	return nil
+}
+
+func (im *Importer) rememberGenre(genre models.Genre) {
+	im.genresByID[genre.GenreID] = genre
+	im.genresByName[strings.ToLower(genre.GenreName)] = genre
+}
+
+func (im *Importer) rememberRanking(ranking models.Ranking) {
+	im.rankingsByKey[strings.ToLower(ranking.RankingName)] = ranking
+	im.rankingsByKey[strconv.Itoa(ranking.RankingValue)] = ranking
+}
+
+func (im *Importer) prepareGenre(rec Record) (models.Genre, error) {
+	var genre models.Genre
+	if rec.Fields != nil {
+		id, err := atoiField(rec.Fields, "genre_id")
+		if err != nil {
+			return genre, err
+		}
+		genre = models.Genre{GenreID: id, GenreName: rec.Fields["genre_name"]}
+	} else if err := json.Unmarshal(rec.Raw, &genre); err != nil {
+		return genre, err
+	}
+	if err := im.validate.Struct(genre); err != nil {
+		return genre, err
+	}
+	im.rememberGenre(genre)
+	return genre, nil
+}
+
+func (im *Importer) prepareRanking(rec Record) (models.Ranking, error) {
+	var ranking models.Ranking
+	if rec.Fields != nil {
+		value, err := atoiField(rec.Fields, "ranking_value")
+		if err != nil {
+			return ranking, err
+		}
+		ranking = models.Ranking{RankingValue: value, RankingName: rec.Fields["ranking_name"]}
+	} else if err := json.Unmarshal(rec.Raw, &ranking); err != nil {
+		return ranking, err
+	}
+	if err := im.validate.Struct(ranking); err != nil {
+		return ranking, err
+	}
+	im.rememberRanking(ranking)
+	return ranking, nil
+}
+
+func (im *Importer) prepareMovie(rec Record) (models.Movie, error) {
+	var movie models.Movie
+	if rec.Fields != nil {
+		movie = movieFromFields(rec.Fields)
+	} else if err := json.Unmarshal(rec.Raw, &movie); err != nil {
+		return movie, err
+	}
+	movie.ID = bson.ObjectID{}
+	movie.ReleaseYear = ReleaseYear(movie.ReleaseDate)
+
+	for i, genre := range movie.Genre {
+		known, ok := im.genresByID[genre.GenreID]
+		if !ok {
+			known, ok = im.genresByName[strings.ToLower(genre.GenreName)]
+		}
+		if !ok {
+			return movie, fmt.Errorf("unknown genre %q", genreLabel(genre))
+		}
+		movie.Genre[i] = known
+	}
+
+	rankingKey := strings.ToLower(movie.Ranking.RankingName)
+	if rankingKey == "" && movie.Ranking.RankingValue != 0 {
+		rankingKey = strconv.Itoa(movie.Ranking.RankingValue)
+	}
+	if rankingKey == "" {
+		rankingKey = strconv.Itoa(UnrankedValue)
+	}
+	ranking, ok := im.rankingsByKey[rankingKey]
+	if !ok {
+		return movie, fmt.Errorf("unknown ranking %q", rankingKey)
+	}
+	movie.Ranking = ranking
+
+	if err := im.validate.Struct(movie); err != nil {
+		return movie, err
+	}
+	return movie, nil
+}
+
+// movieFromFields maps a csv row onto a movie. Genres are a "|" separated
+// list of names or ids, the ranking is given by name or value. Languages and
+// countries are "|" separated codes. Cast and crew only come in through json.
+func movieFromFields(fields map[string]string) models.Movie {
+	movie := models.Movie{
+		ImdbID:           fields["imdb_id"],
+		Title:            fields["title"],
+		PosterPath:       fields["poster_path"],
+		YouTubeID:        fields["youtube_id"],
+		AdminReview:      fields["admin_review"],
+		Ranking:          models.Ranking{RankingName: fields["ranking_name"]},
+		Overview:         fields["overview"],
+		ReleaseDate:      fields["release_date"],
+		OriginalLanguage: fields["original_language"],
+		SpokenLanguages:  splitList(fields["spoken_languages"]),
+		Countries:        splitList(fields["countries"]),
+		AgeCertification: fields["age_certification"],
+	}
+	if value, err := strconv.Atoi(fields["ranking_value"]); err == nil {
+		movie.Ranking.RankingValue = value
+	}
+	if runtime, err := strconv.Atoi(fields["runtime"]); err == nil {
+		movie.Runtime = runtime
+	}
+	for _, item := range strings.Split(fields["genre"], "|") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if id, err := strconv.Atoi(item); err == nil {
+			movie.Genre = append(movie.Genre, models.Genre{GenreID: id})
+		} else {
+			movie.Genre = append(movie.Genre, models.Genre{GenreName: item})
+		}
+	}
+	return movie
+}
+
+func splitList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, "|") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func genreLabel(genre models.Genre) string {
+	switch {
+	case genre.GenreName != "":
+		return genre.GenreName
+	case genre.GenreID != 0:
+		return strconv.Itoa(genre.GenreID)
+	}
+	return ""
+}
+
+func atoiField(fields map[string]string, name string) (int, error) {
+	value, err := strconv.Atoi(fields[name])
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number, got %q", name, fields[name])
+	}
+	return value, nil
+}
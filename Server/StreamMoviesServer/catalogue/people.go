@@ -0,0 +1,104 @@
+package catalogue
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// PrepareMovie fills in the fields derived from others before a movie is
+// saved: the release year and the person_id of every cast and crew member
+func PrepareMovie(ctx context.Context, client *mongo.Client, movie *models.Movie) error {
+	movie.ReleaseYear = ReleaseYear(movie.ReleaseDate)
+	return LinkPeople(ctx, client, movie)
+}
+
+// ReleaseYear returns the year of a YYYY-MM-DD date, or 0 when it is unknown
+func ReleaseYear(releaseDate string) int {
+	if len(releaseDate) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(releaseDate[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
+
+// LinkPeople sets person_id on cast and crew members that lack one, creating
+// entries in the people collection as needed. People are matched by TMDB id
+// when known and by exact name otherwise.
+func LinkPeople(ctx context.Context, client *mongo.Client, movie *models.Movie) error {
+	resolved := map[string]string{}
+	resolve := func(name string, tmdbID int, profilePath string) (string, error) {
+		key := name
+		if tmdbID != 0 {
+			key = "tmdb:" + strconv.Itoa(tmdbID)
+		}
+		if id, ok := resolved[key]; ok {
+			return id, nil
+		}
+		id, err := upsertPerson(ctx, client, name, tmdbID, profilePath)
+		if err != nil {
+			return "", err
+		}
+		resolved[key] = id
+		return id, nil
+	}
+
+	for i := range movie.Cast {
+		member := &movie.Cast[i]
+		if member.PersonID != "" {
+			continue
+		}
+		id, err := resolve(member.Name, member.TMDBID, member.ProfilePath)
+		if err != nil {
+			return err
+		}
+		member.PersonID = id
+	}
+	for i := range movie.Crew {
+		member := &movie.Crew[i]
+		if member.PersonID != "" {
+			continue
+		}
+		id, err := resolve(member.Name, member.TMDBID, member.ProfilePath)
+		if err != nil {
+			return err
+		}
+		member.PersonID = id
+	}
+	return nil
+}
+
+func upsertPerson(ctx context.Context, client *mongo.Client, name string, tmdbID int, profilePath string) (string, error) {
+	filter := bson.M{"name": name, "tmdb_id": bson.M{"$exists": false}}
+	if tmdbID != 0 {
+		filter = bson.M{"tmdb_id": tmdbID}
+	}
+	onInsert := bson.M{
+		"person_id": bson.NewObjectID().Hex(),
+		"name":      name,
+	}
+	if tmdbID != 0 {
+		onInsert["tmdb_id"] = tmdbID
+	}
+	if profilePath != "" {
+		onInsert["profile_path"] = profilePath
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var person models.Person
+	err := database.OpenCollection("people", client).
+		FindOneAndUpdate(ctx, filter, bson.M{"$setOnInsert": onInsert}, opts).
+		Decode(&person)
+	if err != nil {
+		return "", err
+	}
+	return person.PersonID, nil
+}
@@ -0,0 +1,135 @@
+package catalogue
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// UnrankedValue is the reserved ranking of movies without an admin review,
+// it sorts after every real ranking
+const UnrankedValue = 999
+
+const rerankBatchSize = 500
+
+// highlyRankedCount is how many of the best rankings are worth pointing out
+// to users
+const highlyRankedCount = 2
+
+// HighlyRanked is the worst ranking value still worth pointing out, that of
+// the second best ranking on the scale whatever its values are, or 0 when the
+// scale has no rankings
+func HighlyRanked(scale []models.Ranking) int {
+	var values []int
+	for _, ranking := range scale {
+		if ranking.RankingValue > 0 && ranking.RankingValue != UnrankedValue {
+			values = append(values, ranking.RankingValue)
+		}
+	}
+	if len(values) == 0 {
+		return 0
+	}
+	slices.Sort(values)
+	return values[min(highlyRankedCount, len(values))-1]
+}
+
+// Unranked is the reserved ranking as stored in the scale, or its seeded
+// name when the scale lacks it
+func Unranked(scale []models.Ranking) models.Ranking {
+	for _, ranking := range scale {
+		if ranking.RankingValue == UnrankedValue {
+			return ranking
+		}
+	}
+	return models.Ranking{RankingValue: UnrankedValue, RankingName: "Not_Ranked"}
+}
+
+// LoadRankings returns the rankings scale, best first
+func LoadRankings(ctx context.Context, client *mongo.Client) ([]models.Ranking, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "ranking_value", Value: 1}})
+	cursor, err := database.OpenCollection("rankings", client).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var rankings []models.Ranking
+	if err := cursor.All(ctx, &rankings); err != nil {
+		return nil, err
+	}
+	return rankings, nil
+}
+
+// Rerank brings the ranking copied into every movie back in line with the
+// scale. A movie keeps the ranking of the same name with its current value,
+// else the ranking of the same value with its current name, else it becomes
+// unranked. It returns how many movies changed, or would with dryRun.
+func Rerank(ctx context.Context, client *mongo.Client, dryRun bool) (int, error) {
+	scale, err := LoadRankings(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+	byName := make(map[string]models.Ranking, len(scale))
+	byValue := make(map[int]models.Ranking, len(scale))
+	for _, ranking := range scale {
+		byName[strings.ToLower(ranking.RankingName)] = ranking
+		byValue[ranking.RankingValue] = ranking
+	}
+	unranked := Unranked(scale)
+
+	movieCollection := database.OpenCollection("movies", client)
+	opts := options.Find().SetProjection(bson.M{"imdb_id": 1, "ranking": 1})
+	cursor, err := movieCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	changed := 0
+	writes := make([]mongo.WriteModel, 0, rerankBatchSize)
+	flush := func() error {
+		if len(writes) == 0 || dryRun {
+			writes = writes[:0]
+			return nil
+		}
+		_, err := movieCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		writes = writes[:0]
+		return err
+	}
+	for cursor.Next(ctx) {
+		var movie struct {
+			ImdbID  string         `bson:"imdb_id"`
+			Ranking models.Ranking `bson:"ranking"`
+		}
+		if err := cursor.Decode(&movie); err != nil {
+			return changed, err
+		}
+		target, ok := byName[strings.ToLower(movie.Ranking.RankingName)]
+		if !ok {
+			target, ok = byValue[movie.Ranking.RankingValue]
+		}
+		if !ok {
+			target = unranked
+		}
+		if target == movie.Ranking {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"imdb_id": movie.ImdbID}).
+			SetUpdate(database.Versioned(bson.M{"$set": bson.M{"ranking": target}})))
+		changed++
+		if len(writes) == rerankBatchSize {
+			if err := flush(); err != nil {
+				return changed, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return changed, err
+	}
+	return changed, flush()
+}
@@ -0,0 +1,142 @@
+package catalogue
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Record is a single raw entry read from an import file. JSON and NDJSON
+// sources fill Raw, CSV sources fill Fields keyed by the header row.
+type Record struct {
+	Line   int
+	Raw    json.RawMessage
+	Fields map[string]string
+}
+
+// RecordReader returns records one by one and io.EOF once the source is drained.
+// A *RecordError means only the current record is broken and reading may go on.
+type RecordReader interface {
+	Next() (Record, error)
+}
+
+type RecordError struct {
+	Line int
+	Err  error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+func NewRecordReader(r io.Reader, format Format) (RecordReader, error) {
+	switch format {
+	case FormatJSON:
+		return newJSONReader(r)
+	case FormatNDJSON:
+		return &ndjsonReader{scanner: newLineScanner(r)}, nil
+	case FormatCSV:
+		return newCSVReader(r)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type jsonReader struct {
+	dec   *json.Decoder
+	index int
+}
+
+func newJSONReader(r io.Reader) (*jsonReader, error) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("reading json array: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("json import file must contain an array of records")
+	}
+	return &jsonReader{dec: dec}, nil
+}
+
+func (j *jsonReader) Next() (Record, error) {
+	if !j.dec.More() {
+		return Record{}, io.EOF
+	}
+	j.index++
+	var raw json.RawMessage
+	if err := j.dec.Decode(&raw); err != nil {
+		// a broken array cannot be resumed, so stop here
+		return Record{}, fmt.Errorf("record %d: %w", j.index, err)
+	}
+	return Record{Line: j.index, Raw: raw}, nil
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	return scanner
+}
+
+func (n *ndjsonReader) Next() (Record, error) {
+	for n.scanner.Scan() {
+		n.line++
+		line := strings.TrimSpace(n.scanner.Text())
+		if line == "" {
+			continue
+		}
+		return Record{Line: n.line, Raw: json.RawMessage(line)}, nil
+	}
+	if err := n.scanner.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+type csvReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	reader.FieldsPerRecord = len(header)
+	return &csvReader{reader: reader, header: header}, nil
+}
+
+func (c *csvReader) Next() (Record, error) {
+	row, err := c.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Record{Line: parseErr.Line}, &RecordError{Line: parseErr.Line, Err: parseErr.Err}
+		}
+		return Record{}, err
+	}
+	line, _ := c.reader.FieldPos(0)
+	fields := make(map[string]string, len(row))
+	for i, value := range row {
+		fields[c.header[i]] = strings.TrimSpace(value)
+	}
+	return Record{Line: line, Fields: fields}, nil
+}
@@ -0,0 +1,71 @@
+// Package commands contains the command line tools bundled with the server binary
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const usage = `usage: moviestream <command> [flags]
+
+commands:
+  seed       load genres, rankings and movies from a seed directory
+  import     load a single json, csv or ndjson file of one kind
+  export     write movies or users as json, csv or ndjson
+  enrich     fill in movie metadata from TMDB
+  embed      compute the vectors used to find similar movies
+  migrate    apply pending data migrations
+  recommend  rebuild every user's recommendations now
+  rerank     match every movie's ranking to the current rankings scale
+  role       make a user an admin, or a user again
+
+Run "moviestream <command> -h" for the flags of a command.
+`
+
+// Run executes the command named by args[0] and returns the process exit code
+func Run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "seed":
+		return runSeed(args[1:])
+	case "import":
+		return runImport(args[1:])
+	case "export":
+		return runExport(args[1:])
+	case "enrich":
+		return runEnrich(args[1:])
+	case "embed":
+		return runEmbed(args[1:])
+	case "migrate":
+		return runMigrate(args[1:])
+	case "recommend":
+		return runRecommend(args[1:])
+	case "rerank":
+		return runRerank(args[1:])
+	case "role":
+		return runRole(args[1:])
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+	return 2
+}
+
+func connect(ctx context.Context) (*mongo.Client, func(), error) {
+	client := database.Connect()
+	if client == nil {
+		return nil, nil, fmt.Errorf("unable to create mongo client")
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, nil, fmt.Errorf("failed to reach server: %w", err)
+	}
+	return client, func() { _ = client.Disconnect(context.Background()) }, nil
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/embeddings"
+)
+
+func runEmbed(args []string) int {
+	fs := flag.NewFlagSet("embed", flag.ContinueOnError)
+	force := fs.Bool("force", false, "embed every movie, not only missing or stale ones")
+	createIndex := fs.Bool("create-index", false, "create the Atlas vector search index named by MONGODB_VECTOR_INDEX")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx := context.Background()
+	client, disconnect, err := connect(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer disconnect()
+
+	store, err := embeddings.NewStoreFromEnv(client)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *createIndex {
+		if store.VectorIndex == "" {
+			fmt.Fprintln(os.Stderr, "MONGODB_VECTOR_INDEX is not set")
+			return 2
+		}
+		if err := store.EnsureVectorIndex(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, "creating vector index:", err)
+			return 1
+		}
+		fmt.Printf("vector index %s ready for %d dimensions\n", store.VectorIndex, store.Embedder.Dimensions())
+	}
+
+	embedded, err := store.EmbedAll(ctx, *force, func(done int) {
+		fmt.Fprintf(os.Stderr, "embedded %d movies\n", done)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "embedding failed after %d movies: %v\n", embedded, err)
+		return 1
+	}
+	fmt.Printf("embedded %d movies with %s\n", embedded, store.Embedder.Name())
+	return 0
+}
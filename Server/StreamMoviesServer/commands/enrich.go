@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/tmdb"
+)
+
+func runEnrich(args []string) int {
+	fs := flag.NewFlagSet("enrich", flag.ContinueOnError)
+	imdbID := fs.String("imdb-id", "", "enrich a single movie instead of the whole catalogue")
+	onlyMissing := fs.Bool("missing", false, "skip movies that were enriched before")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	tmdbClient, err := tmdb.NewClientFromEnv()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx := context.Background()
+	client, disconnect, err := connect(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer disconnect()
+
+	enricher := &tmdb.Enricher{Client: tmdbClient, Mongo: client}
+	if *imdbID != "" {
+		result, err := enricher.Enrich(ctx, *imdbID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *imdbID, err)
+			return 1
+		}
+		fmt.Printf("%s: enriched %q (tmdb %d)\n", *imdbID, result.Movie.Title, result.Movie.TMDBID)
+		for _, genre := range result.UnmappedGenres {
+			fmt.Printf("%s: tmdb genre %q has no match in genres\n", *imdbID, genre)
+		}
+		return 0
+	}
+
+	enriched, failed, err := enricher.EnrichAll(ctx, *onlyMissing, func(imdbID string, err error) {
+		if err != nil {
+			fmt.Printf("%s: %v\n", imdbID, err)
+		} else {
+			fmt.Printf("%s: enriched\n", imdbID)
+		}
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("%d enriched, %d failed\n", enriched, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
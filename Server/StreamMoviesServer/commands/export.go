@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
+)
+
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	resourceFlag := fs.String("resource", "movies", "what to export: movies or users (users never include secrets)")
+	formatFlag := fs.String("format", "", "json, csv or ndjson (detected from -out, json for stdout)")
+	out := fs.String("out", "", "file to write, stdout when empty")
+	genre := fs.String("genre", "", "genre names or ids, comma separated")
+	ranking := fs.String("ranking", "", "ranking names, comma separated")
+	maxRanking := fs.Int("max-ranking", 0, "highest ranking_value to include")
+	query := fs.String("q", "", "title search")
+	extra := fs.String("filter", "", `further listing filters as a query string, e.g. "year_from=1990&language=en"`)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	resource, err := catalogue.ParseResource(*resourceFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	format := catalogue.FormatJSON
+	switch {
+	case *formatFlag != "":
+		format, err = catalogue.ParseFormat(*formatFlag)
+	case *out != "":
+		format, err = catalogue.FormatFromPath(*out)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	// build the same query values the http endpoint receives so both share one parser
+	values, err := url.ParseQuery(*extra)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid -filter:", err)
+		return 2
+	}
+	if *genre != "" {
+		values.Set("genre", *genre)
+	}
+	if *ranking != "" {
+		values.Set("ranking", *ranking)
+	}
+	if *query != "" {
+		values.Set("q", *query)
+	}
+	if *maxRanking > 0 {
+		values.Set("max_ranking", strconv.Itoa(*maxRanking))
+	}
+	filter, err := catalogue.ParseMovieFilter(values)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx := context.Background()
+	client, disconnect, err := connect(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer disconnect()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	buffered := bufio.NewWriter(w)
+	exporter := &catalogue.Exporter{Client: client}
+
+	var count int
+	if resource == catalogue.ResourceUsers {
+		count, err = exporter.ExportUsers(ctx, format, buffered)
+	} else {
+		count, err = exporter.ExportMovies(ctx, filter, format, buffered)
+	}
+	if flushErr := buffered.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export failed after %d records: %v\n", count, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "exported %d %s\n", count, resource)
+	return 0
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/migrations"
+)
+
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report what pending migrations would change without writing")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx := context.Background()
+	client, disconnect, err := connect(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer disconnect()
+
+	err = migrations.Run(ctx, client, *dryRun, func(res migrations.Result) {
+		switch {
+		case res.Skipped:
+			fmt.Printf("%s: already applied\n", res.Migration.ID)
+		case *dryRun:
+			fmt.Printf("%s: would change %d documents (%s)\n", res.Migration.ID, res.Changed, res.Migration.Description)
+		default:
+			fmt.Printf("%s: changed %d documents (%s)\n", res.Migration.ID, res.Changed, res.Migration.Description)
+		}
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/recommender"
+)
+
+func runRecommend(args []string) int {
+	fs := flag.NewFlagSet("recommend", flag.ContinueOnError)
+	cfg := recommender.ConfigFromEnv()
+	fs.Float64Var(&cfg.Diversity, "diversity", cfg.Diversity, "how strongly similar genres are spread out, 0 turns it off")
+	fs.IntVar(&cfg.PerUser, "per-user", cfg.PerUser, "recommendations stored per user")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx := context.Background()
+	client, disconnect, err := connect(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer disconnect()
+
+	started := time.Now()
+	engine := &recommender.Engine{Client: client, Config: cfg}
+	users, err := engine.Rebuild(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rebuild failed after %d users: %v\n", users, err)
+		return 1
+	}
+	fmt.Printf("recommendations rebuilt for %d users in %s\n", users, time.Since(started).Round(time.Millisecond))
+	return 0
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
+)
+
+func runRerank(args []string) int {
+	fs := flag.NewFlagSet("rerank", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only count the movies that would change")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx := context.Background()
+	client, disconnect, err := connect(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer disconnect()
+
+	changed, err := catalogue.Rerank(ctx, client, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rerank failed after %d movies: %v\n", changed, err)
+		return 1
+	}
+	if *dryRun {
+		fmt.Printf("%d movies would be re-ranked\n", changed)
+		return 0
+	}
+	fmt.Printf("%d movies re-ranked\n", changed)
+	return 0
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/audit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/validation"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// runRole sets a user's role, which is how the first admin is made since
+// everyone registers as USER
+func runRole(args []string) int {
+	fs := flag.NewFlagSet("role", flag.ContinueOnError)
+	email := fs.String("email", "", "email of the user")
+	role := fs.String("role", "ADMIN", "ADMIN or USER")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *email == "" {
+		fmt.Fprintln(os.Stderr, "-email is required")
+		return 2
+	}
+	if err := validation.Struct(models.RoleUpdate{Role: *role}); err != nil {
+		fmt.Fprintln(os.Stderr, "-role must be ADMIN or USER")
+		return 2
+	}
+
+	ctx := context.Background()
+	client, disconnect, err := connect(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer disconnect()
+
+	usersCollection := database.OpenCollection("users", client)
+	var user models.User
+	err = usersCollection.FindOne(ctx, bson.M{"email": *email}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		fmt.Fprintf(os.Stderr, "no user with email %s\n", *email)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	before, _ := audit.Snapshot(ctx, client, "users", "user_id", user.UserID)
+	_, err = usersCollection.UpdateOne(ctx,
+		bson.M{"user_id": user.UserID},
+		bson.M{"$set": bson.M{"role": *role, "update_at": time.Now()}})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	after, _ := audit.Snapshot(ctx, client, "users", "user_id", user.UserID)
+	entry := audit.Entry{
+		ActorRole:  "cli",
+		Action:     "user.role",
+		Method:     "CLI",
+		Path:       "moviestream role",
+		TargetType: "users",
+		TargetID:   user.UserID,
+		Changes:    audit.Diff(before, after),
+	}
+	if err := audit.Record(ctx, client, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "role changed but recording the audit entry failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%s is now %s, the role applies from their next token refresh\n", *email, *role)
+	return 0
+}
@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
+)
+
+var seedExtensions = []string{".json", ".ndjson", ".jsonl", ".csv"}
+
+func runSeed(args []string) int {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	dir := fs.String("dir", "seed", "directory holding genres, rankings and movies files")
+	dryRun := fs.Bool("dry-run", false, "validate records without writing to the database")
+	verbose := fs.Bool("v", false, "print every record, not only failures")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx := context.Background()
+	client, disconnect, err := connect(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer disconnect()
+
+	importer := catalogue.NewImporter(client, *dryRun)
+	failed := false
+	// genres and rankings go first so movies can reference them
+	for _, kind := range []catalogue.Kind{catalogue.KindGenres, catalogue.KindRankings, catalogue.KindMovies} {
+		path, err := findSeedFile(*dir, kind)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if path == "" {
+			fmt.Printf("%s: no seed file in %s, skipping\n", kind, *dir)
+			continue
+		}
+		report, err := importFile(ctx, importer, kind, path, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return 1
+		}
+		printReport(os.Stdout, path, report, *verbose)
+		failed = failed || report.Errors() > 0
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	kindFlag := fs.String("kind", "", "what the file contains: genres, rankings or movies")
+	file := fs.String("file", "", "path of the file to import")
+	formatFlag := fs.String("format", "", "json, csv or ndjson (detected from the extension by default)")
+	dryRun := fs.Bool("dry-run", false, "validate records without writing to the database")
+	verbose := fs.Bool("v", false, "print every record, not only failures")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "-file is required")
+		return 2
+	}
+	kind, err := catalogue.ParseKind(*kindFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx := context.Background()
+	client, disconnect, err := connect(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer disconnect()
+
+	report, err := importFile(ctx, catalogue.NewImporter(client, *dryRun), kind, *file, *formatFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *file, err)
+		return 1
+	}
+	printReport(os.Stdout, *file, report, *verbose)
+	if report.Errors() > 0 {
+		return 1
+	}
+	return 0
+}
+
+func importFile(ctx context.Context, importer *catalogue.Importer, kind catalogue.Kind, path, formatName string) (*catalogue.Report, error) {
+	var (
+		format catalogue.Format
+		err    error
+	)
+	if formatName != "" {
+		format, err = catalogue.ParseFormat(formatName)
+	} else {
+		format, err = catalogue.FormatFromPath(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader, err := catalogue.NewRecordReader(f, format)
+	if err != nil {
+		return nil, err
+	}
+	return importer.Import(ctx, kind, reader)
+}
+
+func findSeedFile(dir string, kind catalogue.Kind) (string, error) {
+	for _, ext := range seedExtensions {
+		path := filepath.Join(dir, string(kind)+ext)
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+func printReport(w io.Writer, source string, report *catalogue.Report, verbose bool) {
+	for _, res := range report.Results {
+		if res.Err == nil && !verbose {
+			continue
+		}
+		line := fmt.Sprintf("%s:%d", source, res.Line)
+		if res.Key != "" {
+			line += " [" + res.Key + "]"
+		}
+		if res.Err != nil {
+			fmt.Fprintf(w, "%s %s: %v\n", line, res.Status, res.Err)
+		} else {
+			fmt.Fprintf(w, "%s %s\n", line, res.Status)
+		}
+	}
+	mode := ""
+	if report.DryRun {
+		mode = " (dry run)"
+	}
+	fmt.Fprintf(w, "%s%s: %d records, %d inserted, %d updated, %d unchanged, %d valid, %d invalid, %d failed\n",
+		report.Kind, mode, len(report.Results),
+		report.Counts[catalogue.StatusInserted], report.Counts[catalogue.StatusUpdated],
+		report.Counts[catalogue.StatusUnchanged], report.Counts[catalogue.StatusValid],
+		report.Counts[catalogue.StatusInvalid], report.Counts[catalogue.StatusFailed])
+}
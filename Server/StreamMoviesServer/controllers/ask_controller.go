@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/discovery"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/embeddings"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/llm"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const defaultAskLimit = 10
+
+// AskMovies godoc
+// @Summary Find movies from a description
+// @Description Turn free text such as "a feel-good sci-fi from the 90s" into catalogue filters with the language model, then return the matching movies with the query that was understood
+// @Tags movies
+// @Accept  json
+// @Produce  json
+// @Param request body models.AskRequest true "What to look for"
+// @Success 200 {object} discovery.Answer
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /movies/ask [post]
+func AskMovies(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.AskRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		if req.Limit == 0 {
+			req.Limit = defaultAskLimit
+		}
+
+		model, err := llm.NewModelFromEnv()
+		if err != nil {
+			logger.WarnContext(c, "language model unavailable", "error", err)
+			c.Error(apperr.Unavailable("llm_unavailable", "Language model is not configured", err))
+			return
+		}
+		asker := &discovery.Asker{Client: client, Model: model}
+		if store, err := embeddings.NewStoreFromEnv(client); err == nil {
+			asker.Embeddings = store
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		answer, err := asker.Ask(ctx, req.Text, req.Limit)
+		var invalid *discovery.ValidationError
+		switch {
+		case errors.As(err, &invalid):
+			c.Error(apperr.Upstream("llm_unparseable", "Could not understand the request", err).With("reason", invalid.Reason))
+			return
+		case errors.Is(err, discovery.ErrModelFailed):
+			c.Error(apperr.Upstream("llm_failed", "Language model request failed", err))
+			return
+		case err != nil:
+			c.Error(apperr.Internal("Failed to find movies", err))
+			return
+		}
+		c.JSON(http.StatusOK, answer)
+	}
+}
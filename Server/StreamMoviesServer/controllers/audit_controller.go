@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/audit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// GetAuditLog godoc
+// @Summary Get the audit log
+// @Description Get recorded administrative changes, newest first, with the fields each one changed. Admin only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param actor_id query string false "User who made the change"
+// @Param action query string false "Action such as movie.create or genre.delete"
+// @Param target_type query string false "Collection of the changed document"
+// @Param target_id query string false "Key of the changed document"
+// @Param from query string false "Earliest time, RFC 3339"
+// @Param to query string false "Time before which entries end, RFC 3339"
+// @Param page query int false "Page number, from 1"
+// @Param page_size query int false "Entries per page, at most 100"
+// @Success 200 {object} models.Page[audit.Entry]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/audit [get]
+func GetAuditLog(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := audit.Filter{
+			ActorID:    c.Query("actor_id"),
+			Action:     c.Query("action"),
+			TargetType: c.Query("target_type"),
+			TargetID:   c.Query("target_id"),
+		}
+		for param, value := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+			if c.Query(param) == "" {
+				continue
+			}
+			parsed, err := time.Parse(time.RFC3339, c.Query(param))
+			if err != nil {
+				c.Error(apperr.InvalidParameter("Invalid " + param + ", expected RFC 3339"))
+				return
+			}
+			*value = parsed
+		}
+		page, pageSize := getPagination(c)
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		entries, total, err := audit.List(ctx, client, filter, (page-1)*pageSize, pageSize)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to fetch audit log", err))
+			return
+		}
+		c.JSON(http.StatusOK, models.Page[audit.Entry]{Items: entries, Page: page, PageSize: pageSize, Total: total})
+	}
+}
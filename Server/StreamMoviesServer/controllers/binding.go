@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/validation"
+)
+
+// bindJSON decodes the request body into v and validates it. When either
+// fails the error is reported on c and false is returned.
+func bindJSON(c *gin.Context, v any) bool {
+	err := c.ShouldBindJSON(v)
+	if err == nil {
+		return true
+	}
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		c.Error(apperr.Validation(err))
+	} else {
+		c.Error(apperr.InvalidBody(err))
+	}
+	return false
+}
+
+// imdbIDParam returns the imdb_id path parameter, reporting an error on c
+// when it is not an IMDb id
+func imdbIDParam(c *gin.Context) (string, bool) {
+	imdbID := c.Param("imdb_id")
+	if !validation.IsImdbID(imdbID) {
+		c.Error(apperr.InvalidParameter("Invalid imdb_id, expected an IMDb id such as tt0111161"))
+		return "", false
+	}
+	return imdbID, true
+}
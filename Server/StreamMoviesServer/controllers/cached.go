@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/cache"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// hot reads go through hotCache, entries live for hotTTL. Handlers that
+// change what is cached forget it, other instances and the command line see
+// the change once the entry expires.
+var (
+	hotCache cache.Cache = cache.Nop{}
+	hotTTL   time.Duration
+)
+
+const (
+	genresKey             = "genres"
+	rankingsKey           = "rankings"
+	moviePrefix           = "movie:"
+	recommendationsPrefix = "recommendations:"
+)
+
+// InitCache sets the cache for genres, rankings, movies and recommendations
+func InitCache(c cache.Cache, ttl time.Duration) {
+	hotCache, hotTTL = c, ttl
+}
+
+func cachedGenres(ctx context.Context, client *mongo.Client) ([]models.Genre, error) {
+	return cache.Load(ctx, hotCache, genresKey, hotTTL, func(ctx context.Context) ([]models.Genre, error) {
+		cursor, err := database.OpenCollection("genres", client).Find(ctx, bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		var genres []models.Genre
+		err = cursor.All(ctx, &genres)
+		return genres, err
+	})
+}
+
+func cachedRankings(ctx context.Context, client *mongo.Client) ([]models.Ranking, error) {
+	return cache.Load(ctx, hotCache, rankingsKey, hotTTL, func(ctx context.Context) ([]models.Ranking, error) {
+		return catalogue.LoadRankings(ctx, client)
+	})
+}
+
+// cachedMovie returns mongo.ErrNoDocuments for unknown movies, which are not
+// cached
+func cachedMovie(ctx context.Context, client *mongo.Client, imdbID string) (models.Movie, error) {
+	return cache.Load(ctx, hotCache, moviePrefix+imdbID, hotTTL, func(ctx context.Context) (models.Movie, error) {
+		var movie models.Movie
+		opts := options.FindOne().SetProjection(movieProjection)
+		err := database.OpenCollection("movies", client).FindOne(ctx, bson.M{"imdb_id": imdbID}, opts).Decode(&movie)
+		return movie, err
+	})
+}
+
+func cachedRecommendations(ctx context.Context, client *mongo.Client, userID string, limit int64) ([]models.RecommendedMovie, error) {
+	return cache.Load(ctx, hotCache, recommendationsPrefix+userID, hotTTL, func(ctx context.Context) ([]models.RecommendedMovie, error) {
+		return storedRecommendations(ctx, client, userID, limit)
+	})
+}
+
+// forget drops cached entries, failures only leave them until they expire
+func forget(ctx context.Context, keys ...string) {
+	if err := hotCache.Delete(ctx, keys...); err != nil {
+		logger.WarnContext(ctx, "cache invalidation failed", "keys", keys, "error", err)
+	}
+}
+
+func forgetPrefix(ctx context.Context, prefix string) {
+	if err := hotCache.DeletePrefix(ctx, prefix); err != nil {
+		logger.WarnContext(ctx, "cache invalidation failed", "prefix", prefix, "error", err)
+	}
+}
+
+// forgetMovie drops a changed movie and the recommendations showing it
+func forgetMovie(ctx context.Context, imdbID string) {
+	forget(ctx, moviePrefix+imdbID)
+	forgetPrefix(ctx, recommendationsPrefix)
+}
+
+// forgetMovies is forgetMovie for changes to many movies at once, such as a
+// genre or person renamed in all of them
+func forgetMovies(ctx context.Context) {
+	forgetPrefix(ctx, moviePrefix)
+	forgetPrefix(ctx, recommendationsPrefix)
+}
+
+// forgetUserRecommendations drops a user's recommendations after they saved,
+// watched or rated a movie, which is no longer recommended to them
+func forgetUserRecommendations(ctx context.Context, userID string) {
+	forget(ctx, recommendationsPrefix+userID)
+}
+
+// ForgetRecommendations drops every user's recommendations, for after the
+// recommendation job rebuilt them
+func ForgetRecommendations(ctx context.Context) {
+	forgetPrefix(ctx, recommendationsPrefix)
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+)
+
+// movieETag is the strong ETag of a movie at version
+func movieETag(version int64) string {
+	return fmt.Sprintf(`"v%d"`, version)
+}
+
+// moviesETag is the strong ETag of a list of movies, derived from their ids
+// and versions in order
+func moviesETag(movies []models.Movie) string {
+	hash := sha256.New()
+	for _, movie := range movies {
+		fmt.Fprintf(hash, "%s:%d\n", movie.ImdbID, movie.Version)
+	}
+	return hashETag(hash.Sum(nil))
+}
+
+// contentETag is the strong ETag of value's JSON encoding, for documents
+// without versions
+func contentETag(value any) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hashETag(sum[:]), nil
+}
+
+func hashETag(sum []byte) string {
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// lastModified is the latest change to movies, zero when it is not known for
+// all of them
+func lastModified(movies []models.Movie) time.Time {
+	var latest time.Time
+	for _, movie := range movies {
+		if movie.UpdatedAt.IsZero() {
+			return time.Time{}
+		}
+		if movie.UpdatedAt.After(latest) {
+			latest = movie.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// notModified sets the validators of the representation about to be sent
+// and answers 304 when If-None-Match, or without it If-Modified-Since, shows
+// the client already has it. A zero modified leaves Last-Modified out.
+func notModified(c *gin.Context, etag string, modified time.Time) bool {
+	c.Header("ETag", etag)
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if !etagMatches(match, etag, false) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+		// Last-Modified only has whole seconds
+		if err != nil || modified.IsZero() || modified.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header lists etag
+// or is "*". If-Match compares strongly, where weak tags never match.
+func etagMatches(header, etag string, strong bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak, ok := strings.CutPrefix(candidate, "W/"); ok {
+			if strong {
+				continue
+			}
+			candidate = weak
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// versionFilter matches movies at version, where version 0 stands for
+// movies written before versions were kept
+func versionFilter(version int64) any {
+	if version == 0 {
+		return nil
+	}
+	return version
+}
+
+func movieChanged() *apperr.Error {
+	return apperr.PreconditionFailed("movie_changed", "The movie was changed since it was loaded, reload it and try again")
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/events"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/metrics"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/utils"
+)
+
+// heartbeatInterval keeps idle streams from being cut by proxies
+const heartbeatInterval = 25 * time.Second
+
+// eventBus carries the events handlers publish to the open streams
+var eventBus events.Bus = events.NewHub()
+
+// InitEvents sets the bus events are published on and streamed from
+func InitEvents(bus events.Bus) {
+	eventBus = bus
+}
+
+// publish tells the open streams about a change, for userID alone or for
+// everyone when it is empty. The change already happened, so failures are
+// only logged.
+func publish(ctx context.Context, eventType, userID string, data any) {
+	event, err := events.New(eventType, userID, data)
+	if err == nil {
+		err = eventBus.Publish(ctx, event)
+	}
+	if err != nil {
+		metrics.EventPublished(eventType, metrics.Error)
+		logger.WarnContext(ctx, "publishing event failed", "type", eventType, "error", err)
+		return
+	}
+	metrics.EventPublished(eventType, metrics.Success)
+}
+
+// StreamEvents godoc
+// @Summary Stream catalogue events
+// @Description Server-Sent Events about changes made by anyone: movie.created and movie.updated with the imdb_id and version of the movie, genre.changed with the action (created, renamed or deleted) and the genre, catalogue.reranked with the action (updated, deleted or rerank) and how many movies changed ranking when a ranking was edited or deleted or movies were re-ranked. The user's own stream also gets ranking.completed when a review they sent was ranked, and user.role_changed when their role changed, after which a token refresh applies it. Each event's data is JSON and its id can be sent back as Last-Event-ID to catch up on recent events after a reconnect. A comment is sent every 25 seconds to keep the stream open.
+// @Tags events
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "id of the last event received"
+// @Success 200 {object} events.Event
+// @Failure 401 {object} models.ErrorResponse
+// @Router /events [get]
+func StreamEvents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIDFromContext(c)
+		if err != nil {
+			c.Error(apperr.Unauthenticated(err))
+			return
+		}
+		subscription := eventBus.Subscribe(userID, c.GetHeader("Last-Event-ID"))
+		defer subscription.Close()
+		metrics.EventStreamOpened()
+		defer metrics.EventStreamClosed()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-store")
+		// stops nginx from buffering the stream
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case event, ok := <-subscription.Events():
+				if !ok {
+					// fell behind, the client reconnects with Last-Event-ID
+					return false
+				}
+				c.Render(-1, sse.Event{Id: event.ID, Event: event.Type, Data: event.Data})
+				return true
+			case <-heartbeat.C:
+				_, err := fmt.Fprint(w, ": ping\n\n")
+				return err == nil
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ExportCatalogue godoc
+// @Summary Export the catalogue
+// @Description Stream all movies (or users without secrets) as csv, json or ndjson. Admin only.
+// @Tags admin
+// @Produce  json
+// @Produce  text/csv
+// @Produce  application/x-ndjson
+// @Param resource query string false "movies (default) or users"
+// @Param format query string false "json (default), csv or ndjson"
+// @Param genre query string false "Genre names or ids, comma separated"
+// @Param ranking query string false "Ranking names, comma separated"
+// @Param max_ranking query int false "Highest ranking_value to include"
+// @Param q query string false "Title search"
+// @Param year query int false "Release year"
+// @Param year_from query int false "Earliest release year"
+// @Param year_to query int false "Latest release year"
+// @Param language query string false "ISO 639-1 original or spoken languages, comma separated"
+// @Param country query string false "ISO 3166-1 production countries, comma separated"
+// @Param certification query string false "Age certifications, comma separated"
+// @Param min_runtime query int false "Shortest runtime in minutes"
+// @Param max_runtime query int false "Longest runtime in minutes"
+// @Param person query string false "person_id credited in cast or crew"
+// @Param person_role query string false "cast, crew or a crew job such as Director"
+// @Success 200 {array} models.Movie
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/export [get]
+func ExportCatalogue(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resource, err := catalogue.ParseResource(c.Query("resource"))
+		if err != nil {
+			c.Error(apperr.InvalidParameter(err.Error()))
+			return
+		}
+		format := catalogue.FormatJSON
+		if formatName := c.Query("format"); formatName != "" {
+			format, err = catalogue.ParseFormat(formatName)
+			if err != nil {
+				c.Error(apperr.InvalidParameter(err.Error()))
+				return
+			}
+		}
+		filter, err := catalogue.ParseMovieFilter(c.Request.URL.Query())
+		if err != nil {
+			c.Error(apperr.InvalidParameter(err.Error()))
+			return
+		}
+
+		filename := fmt.Sprintf("%s-%s.%s", resource, time.Now().UTC().Format("20060102-150405"), format)
+		// set once the query delivered, until then errors get a problem response
+		start := func() {
+			c.Header("Content-Type", format.ContentType())
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+			c.Status(http.StatusOK)
+		}
+		exporter := &catalogue.Exporter{Client: client, Start: start, Flush: c.Writer.Flush}
+		var count int
+		if resource == catalogue.ResourceUsers {
+			count, err = exporter.ExportUsers(c.Request.Context(), format, c.Writer)
+		} else {
+			count, err = exporter.ExportMovies(c.Request.Context(), filter, format, c.Writer)
+		}
+		if err != nil {
+			if !c.Writer.Written() {
+				c.Header("Content-Type", "")
+				c.Header("Content-Disposition", "")
+				c.Error(apperr.Internal("Failed to export "+string(resource), err))
+				return
+			}
+			// headers are already on the wire, all we can do is cut the stream short
+			logger.ErrorContext(c, "export failed mid-stream", "resource", resource, "records", count, "error", err)
+			c.Abort()
+		}
+	}
+}
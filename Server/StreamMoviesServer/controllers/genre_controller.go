@@ -0,0 +1,416 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/audit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/events"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// GetGenres godoc
+// @Summary Get all genres
+// @Description Get a list of all movie genres, with with_counts=true each genre carries the number of movies that have it
+// @Tags genres
+// @Accept  json
+// @Produce  json
+// @Param with_counts query bool false "Include movie counts"
+// @Param If-None-Match header string false "ETag of the list the client has"
+// @Success 200 {array} models.GenreWithCount
+// @Success 304 "Not modified"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /genres [get]
+func GetGenres(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		results, err := cachedGenres(ctx, client)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to fetch genres", err))
+			return
+		}
+		var body any = results
+		if c.Query("with_counts") == "true" {
+			counts, err := genreMovieCounts(ctx, client)
+			if err != nil {
+				c.Error(apperr.Internal("Failed to count movies per genre", err))
+				return
+			}
+			withCounts := make([]models.GenreWithCount, len(results))
+			for i, genre := range results {
+				withCounts[i] = models.GenreWithCount{Genre: genre, MovieCount: counts[genre.GenreID]}
+			}
+			body = withCounts
+		}
+		// genres carry no versions, their ETag comes from the content
+		etag, err := contentETag(body)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to encode genres", err))
+			return
+		}
+		if notModified(c, etag, time.Time{}) {
+			return
+		}
+		c.JSON(http.StatusOK, body)
+	}
+}
+
+// genreMovieCounts returns the number of movies per genre_id
+func genreMovieCounts(ctx context.Context, client *mongo.Client) (map[int]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$unwind", Value: "$genre"}},
+		{{Key: "$group", Value: bson.M{"_id": "$genre.genre_id", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := database.OpenCollection("movies", client).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		GenreID int `bson:"_id"`
+		Count   int `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.GenreID] = row.Count
+	}
+	return counts, nil
+}
+
+// AddGenre godoc
+// @Summary Add a genre
+// @Description Add a new genre. Sending a genre that exists with the same name again returns it unchanged, existing genres are renamed with PUT /genre/{id}. Admin only.
+// @Tags genres
+// @Accept  json
+// @Produce  json
+// @Param genre body models.Genre true "Genre object"
+// @Success 200 {object} models.Genre
+// @Success 201 {object} models.Genre
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /genre [post]
+func AddGenre(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var genre models.Genre
+		if !bindJSON(c, &genre) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		genresCollection := database.OpenCollection("genres", client)
+		var existing models.Genre
+		err := genresCollection.FindOne(ctx, bson.M{"genre_id": genre.GenreID}).Decode(&existing)
+		if err == nil {
+			if existing.GenreName != genre.GenreName {
+				c.Error(apperr.Conflict("genre_exists", "Genre already exists under another name, rename it with PUT /genre/{id}"))
+				return
+			}
+			c.Set(audit.TargetContextKey, genre.GenreID)
+			c.JSON(http.StatusOK, existing)
+			return
+		}
+		if err != mongo.ErrNoDocuments {
+			c.Error(apperr.Internal("Failed to add genre", err))
+			return
+		}
+		taken, err := genreNameTaken(ctx, client, genre.GenreName, genre.GenreID)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to add genre", err))
+			return
+		}
+		if taken {
+			c.Error(apperr.Conflict("genre_exists", "Another genre already has this name"))
+			return
+		}
+
+		c.Set(audit.TargetContextKey, genre.GenreID)
+		_, err = genresCollection.InsertOne(ctx, genre)
+		if mongo.IsDuplicateKeyError(err) {
+			// another request added the genre since the check
+			c.Error(apperr.Conflict("genre_exists", "Genre already exists, rename it with PUT /genre/{id}"))
+			return
+		}
+		if err != nil {
+			c.Error(apperr.Internal("Failed to add genre", err))
+			return
+		}
+		forget(ctx, genresKey)
+		publish(ctx, events.GenreChanged, "", events.GenreChange{Action: "created", GenreID: genre.GenreID, GenreName: genre.GenreName})
+		c.JSON(http.StatusCreated, genre)
+	}
+}
+
+// genreNameTaken reports whether a genre other than genreID has name,
+// ignoring case
+func genreNameTaken(ctx context.Context, client *mongo.Client, name string, genreID int) (bool, error) {
+	taken, err := database.OpenCollection("genres", client).CountDocuments(ctx, bson.M{
+		"genre_id":   bson.M{"$ne": genreID},
+		"genre_name": bson.M{"$regex": "^" + regexp.QuoteMeta(name) + "$", "$options": "i"},
+	})
+	return taken > 0, err
+}
+
+// UpdateGenre godoc
+// @Summary Rename a genre
+// @Description Rename a genre and the copies of its name kept in movies and users' favourite genres, all in one transaction, so MongoDB must run as a replica set or sharded cluster. Admin only.
+// @Tags genres
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Genre ID"
+// @Param genre body models.GenreUpdate true "New name"
+// @Success 200 {object} models.Genre
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /genre/{id} [put]
+func UpdateGenre(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		genreID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.Error(apperr.InvalidParameter("Invalid genre id"))
+			return
+		}
+		var req models.GenreUpdate
+		if !bindJSON(c, &req) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		genresCollection := database.OpenCollection("genres", client)
+		count, err := genresCollection.CountDocuments(ctx, bson.M{"genre_id": genreID})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to update genre", err))
+			return
+		}
+		if count == 0 {
+			c.Error(apperr.NotFound("genre_not_found", "Genre not found"))
+			return
+		}
+		taken, err := genreNameTaken(ctx, client, req.GenreName, genreID)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to update genre", err))
+			return
+		}
+		if taken {
+			c.Error(apperr.Conflict("genre_exists", "Another genre already has this name"))
+			return
+		}
+
+		genre := models.Genre{GenreID: genreID, GenreName: req.GenreName}
+		if err := renameGenre(ctx, client, genre); err != nil {
+			c.Error(apperr.Internal("Failed to update genre", err))
+			return
+		}
+		c.JSON(http.StatusOK, genre)
+	}
+}
+
+// DeleteGenre godoc
+// @Summary Delete a genre
+// @Description Delete a genre and remove it from users' favourite genres, in one transaction, so MongoDB must run as a replica set or sharded cluster. A genre movies still have is only deleted with force=true and reassign_to naming the genre that replaces it in those movies and favourites. Admin only.
+// @Tags genres
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Genre ID"
+// @Param force query bool false "Delete even when movies have the genre"
+// @Param reassign_to query int false "Genre ID that replaces the deleted one, required with force"
+// @Success 200 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /genre/{id} [delete]
+func DeleteGenre(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		genreID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.Error(apperr.InvalidParameter("Invalid genre id"))
+			return
+		}
+		force := c.Query("force") == "true"
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		genresCollection := database.OpenCollection("genres", client)
+		count, err := genresCollection.CountDocuments(ctx, bson.M{"genre_id": genreID})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to delete genre", err))
+			return
+		}
+		if count == 0 {
+			c.Error(apperr.NotFound("genre_not_found", "Genre not found"))
+			return
+		}
+
+		var replacement *models.Genre
+		if value := c.Query("reassign_to"); value != "" {
+			replacementID, err := strconv.Atoi(value)
+			if err != nil || replacementID == genreID {
+				c.Error(apperr.InvalidParameter("Invalid reassign_to genre id"))
+				return
+			}
+			var genre models.Genre
+			err = genresCollection.FindOne(ctx, bson.M{"genre_id": replacementID}).Decode(&genre)
+			if err == mongo.ErrNoDocuments {
+				c.Error(apperr.BadRequest("genre_not_found", "reassign_to genre not found"))
+				return
+			}
+			if err != nil {
+				c.Error(apperr.Internal("Failed to delete genre", err))
+				return
+			}
+			replacement = &genre
+		}
+
+		used, err := database.OpenCollection("movies", client).CountDocuments(ctx, bson.M{"genre.genre_id": genreID})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to delete genre", err))
+			return
+		}
+		if used > 0 && !force {
+			c.Error(apperr.Conflict("genre_in_use", "Genre is still used by movies, pass force=true and reassign_to").With("movies", used))
+			return
+		}
+		if used > 0 && replacement == nil {
+			c.Error(apperr.InvalidParameter("reassign_to is required to delete a genre movies still have"))
+			return
+		}
+
+		if err := removeGenre(ctx, client, genreID, replacement); err != nil {
+			c.Error(apperr.Internal("Failed to delete genre", err))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "genre deleted", "movies": used})
+	}
+}
+
+// genreCopy is an embedded genre array holding copies of a genre, versioned
+// when changes to it are changes to a movie
+type genreCopy struct {
+	collection, field string
+	versioned         bool
+}
+
+var genreCopies = []genreCopy{
+	{"movies", "genre", true},
+	{"users", "favourite_genres", false},
+}
+
+func (g genreCopy) update(update bson.M) bson.M {
+	if g.versioned {
+		return database.Versioned(update)
+	}
+	return update
+}
+
+// renameGenre sets the genre's name and rewrites the copies in movies and
+// users within one transaction, so no reader sees the old and new name mixed
+func renameGenre(ctx context.Context, client *mongo.Client, genre models.Genre) error {
+	defer forgetGenres(ctx)
+	err := inTransaction(ctx, client, func(ctx context.Context) error {
+		_, err := database.OpenCollection("genres", client).UpdateOne(ctx,
+			bson.M{"genre_id": genre.GenreID},
+			bson.M{"$set": bson.M{"genre_name": genre.GenreName}})
+		if err != nil {
+			return err
+		}
+		for _, copies := range genreCopies {
+			update := copies.update(bson.M{"$set": bson.M{copies.field + ".$[g].genre_name": genre.GenreName}})
+			opts := options.UpdateMany().SetArrayFilters([]any{bson.M{"g.genre_id": genre.GenreID}})
+			filter := bson.M{copies.field + ".genre_id": genre.GenreID}
+			if _, err := database.OpenCollection(copies.collection, client).UpdateMany(ctx, filter, update, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		publish(ctx, events.GenreChanged, "", events.GenreChange{Action: "renamed", GenreID: genre.GenreID, GenreName: genre.GenreName})
+	}
+	return err
+}
+
+// removeGenre deletes a genre and its copies in movies and users, or swaps
+// them for replacement when given. Arrays that already hold the replacement
+// just lose the deleted genre so it is never listed twice.
+func removeGenre(ctx context.Context, client *mongo.Client, genreID int, replacement *models.Genre) error {
+	defer forgetGenres(ctx)
+	err := inTransaction(ctx, client, func(ctx context.Context) error {
+		for _, copies := range genreCopies {
+			collection := database.OpenCollection(copies.collection, client)
+			pull := copies.update(bson.M{"$pull": bson.M{copies.field: bson.M{"genre_id": genreID}}})
+			if replacement == nil {
+				if _, err := collection.UpdateMany(ctx, bson.M{copies.field + ".genre_id": genreID}, pull); err != nil {
+					return err
+				}
+				continue
+			}
+			both := bson.M{"$and": bson.A{
+				bson.M{copies.field + ".genre_id": genreID},
+				bson.M{copies.field + ".genre_id": replacement.GenreID},
+			}}
+			if _, err := collection.UpdateMany(ctx, both, pull); err != nil {
+				return err
+			}
+			swap := copies.update(bson.M{"$set": bson.M{copies.field + ".$[g]": replacement}})
+			opts := options.UpdateMany().SetArrayFilters([]any{bson.M{"g.genre_id": genreID}})
+			if _, err := collection.UpdateMany(ctx, bson.M{copies.field + ".genre_id": genreID}, swap, opts); err != nil {
+				return err
+			}
+		}
+		_, err := database.OpenCollection("genres", client).DeleteOne(ctx, bson.M{"genre_id": genreID})
+		return err
+	})
+	if err == nil {
+		change := events.GenreChange{Action: "deleted", GenreID: genreID}
+		if replacement != nil {
+			change.ReplacedBy = replacement.GenreID
+		}
+		publish(ctx, events.GenreChanged, "", change)
+	}
+	return err
+}
+
+// forgetGenres drops the cached genres and every movie holding a copy of
+// them, also after a failed transaction as it may have been committed
+func forgetGenres(ctx context.Context) {
+	forget(ctx, genresKey)
+	forgetMovies(ctx)
+}
+
+// inTransaction runs fn in a transaction, which needs a replica set or a
+// sharded cluster such as Atlas
+func inTransaction(ctx context.Context, client *mongo.Client, fn func(ctx context.Context) error) error {
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(ctx context.Context) (any, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
@@ -0,0 +1,327 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	HistoryCollection = "watch_history"
+
+	// completedShare is how much of a movie has to be watched to count as completed
+	completedShare          = 0.95
+	historyRetentionIndex   = "history_retention"
+	defaultContinueWatching = 10
+)
+
+// RecordWatchEvent godoc
+// @Summary Record watch progress
+// @Description Store how far the current user got in a movie. Replaying an event, or sending one older than the stored progress, changes nothing.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param event body models.WatchEvent true "Watch event"
+// @Success 200 {object} models.HistoryEntry
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/history [post]
+func RecordWatchEvent(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIDFromContext(c)
+		if err != nil {
+			c.Error(apperr.Unauthenticated(err))
+			return
+		}
+		var event models.WatchEvent
+		if !bindJSON(c, &event) {
+			return
+		}
+		if event.WatchedAt.IsZero() || event.WatchedAt.After(time.Now()) {
+			event.WatchedAt = time.Now()
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		count, err := database.OpenCollection("movies", client).CountDocuments(ctx, bson.M{"imdb_id": event.ImdbID})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to record history", err))
+			return
+		}
+		if count == 0 {
+			c.Error(apperr.NotFound("movie_not_found", "Movie not found"))
+			return
+		}
+
+		entry, err := recordWatchEvent(ctx, client, userID, event)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to record history", err))
+			return
+		}
+		forgetUserRecommendations(ctx, userID)
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// recordWatchEvent keeps one entry per user and movie holding the newest
+// progress. The entry is only updated when the event is at least as recent as
+// it, so retries and events arriving out of order are harmless.
+func recordWatchEvent(ctx context.Context, client *mongo.Client, userID string, event models.WatchEvent) (*models.HistoryEntry, error) {
+	completed := event.Completed ||
+		(event.DurationSeconds > 0 && float64(event.PositionSeconds) >= completedShare*float64(event.DurationSeconds))
+	progress := bson.M{
+		"position_seconds": event.PositionSeconds,
+		"duration_seconds": event.DurationSeconds,
+		"completed":        completed,
+		"device":           event.Device,
+		"last_watched_at":  event.WatchedAt,
+	}
+	key := bson.M{"user_id": userID, "imdb_id": event.ImdbID}
+	historyCollection := database.OpenCollection(HistoryCollection, client)
+
+	newer := bson.M{"user_id": userID, "imdb_id": event.ImdbID, "last_watched_at": bson.M{"$lte": event.WatchedAt}}
+	update := bson.M{"$set": progress, "$min": bson.M{"first_watched_at": event.WatchedAt}}
+	result, err := historyCollection.UpdateOne(ctx, newer, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		// either the first event for this movie or an older one, which leaves
+		// the stored entry as it is
+		insert := bson.M{"user_id": userID, "imdb_id": event.ImdbID, "first_watched_at": event.WatchedAt}
+		for field, value := range progress {
+			insert[field] = value
+		}
+		_, err := historyCollection.UpdateOne(ctx, key, bson.M{"$setOnInsert": insert}, options.UpdateOne().SetUpsert(true))
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+	}
+
+	var entry models.HistoryEntry
+	if err := historyCollection.FindOne(ctx, key).Decode(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetHistory godoc
+// @Summary Get watch history
+// @Description Get a page of the movies the current user watched, most recent first
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Items per page, at most 100"
+// @Success 200 {object} models.Page[models.HistoryEntry]
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/history [get]
+func GetHistory(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		listHistory(c, client, false)
+	}
+}
+
+// GetContinueWatching godoc
+// @Summary Continue watching
+// @Description Get the movies the current user started but did not finish, most recent first
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Items per page, at most 100"
+// @Success 200 {object} models.Page[models.HistoryEntry]
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/continue-watching [get]
+func GetContinueWatching(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		listHistory(c, client, true)
+	}
+}
+
+func listHistory(c *gin.Context, client *mongo.Client, unfinished bool) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.Error(apperr.Unauthenticated(err))
+		return
+	}
+	page, pageSize := getPagination(c)
+	if unfinished && c.Query("page_size") == "" {
+		pageSize = defaultContinueWatching
+	}
+
+	ctx, cancel := context.WithTimeout(c, 100*time.Second)
+	defer cancel()
+
+	match := bson.M{"user_id": userID}
+	if unfinished {
+		match["completed"] = false
+		match["position_seconds"] = bson.M{"$gt": 0}
+	}
+	historyCollection := database.OpenCollection(HistoryCollection, client)
+	total, err := historyCollection.CountDocuments(ctx, match)
+	if err != nil {
+		c.Error(apperr.Internal("Failed to count history", err))
+		return
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "last_watched_at", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$skip", Value: (page - 1) * pageSize}},
+		{{Key: "$limit", Value: pageSize}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "movies",
+			"localField":   "imdb_id",
+			"foreignField": "imdb_id",
+			"as":           "movie",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$movie", "preserveNullAndEmptyArrays": true}}},
+	}
+	cursor, err := historyCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch history", err))
+		return
+	}
+	defer cursor.Close(ctx)
+
+	items := []models.HistoryEntry{}
+	if err := cursor.All(ctx, &items); err != nil {
+		c.Error(apperr.Internal("Failed to decode history", err))
+		return
+	}
+	c.JSON(http.StatusOK, models.Page[models.HistoryEntry]{Items: items, Page: page, PageSize: pageSize, Total: total})
+}
+
+// ClearHistory godoc
+// @Summary Clear watch history
+// @Description Delete the current user's watch history, or only what was last watched before a time
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param before query string false "RFC 3339 time, only older entries are deleted"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/history [delete]
+func ClearHistory(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIDFromContext(c)
+		if err != nil {
+			c.Error(apperr.Unauthenticated(err))
+			return
+		}
+		filter := bson.M{"user_id": userID}
+		if before := c.Query("before"); before != "" {
+			at, err := time.Parse(time.RFC3339, before)
+			if err != nil {
+				c.Error(apperr.InvalidParameter("before must be an RFC 3339 time"))
+				return
+			}
+			filter["last_watched_at"] = bson.M{"$lt": at}
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		result, err := database.OpenCollection(HistoryCollection, client).DeleteMany(ctx, filter)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to clear history", err))
+			return
+		}
+		forgetUserRecommendations(ctx, userID)
+		c.JSON(http.StatusOK, gin.H{"deleted": result.DeletedCount})
+	}
+}
+
+// DeleteHistoryEntry godoc
+// @Summary Delete a watch history entry
+// @Description Remove one movie from the current user's watch history
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param imdb_id path string true "IMDb ID"
+// @Success 200 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/history/{imdb_id} [delete]
+func DeleteHistoryEntry(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIDFromContext(c)
+		if err != nil {
+			c.Error(apperr.Unauthenticated(err))
+			return
+		}
+
+		imdbID, ok := imdbIDParam(c)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		result, err := database.OpenCollection(HistoryCollection, client).DeleteOne(ctx, bson.M{"user_id": userID, "imdb_id": imdbID})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to delete history entry", err))
+			return
+		}
+		if result.DeletedCount == 0 {
+			c.Error(apperr.NotFound("history_entry_not_found", "Movie is not in the history"))
+			return
+		}
+		forgetUserRecommendations(ctx, userID)
+		c.JSON(http.StatusOK, gin.H{"message": "history entry deleted"})
+	}
+}
+
+// getCompletedIDs returns the imdb ids the user watched to the end
+func getCompletedIDs(ctx context.Context, client *mongo.Client, userID string) ([]string, error) {
+	opts := options.Find().SetProjection(bson.M{"imdb_id": 1, "_id": 0})
+	cursor, err := database.OpenCollection(HistoryCollection, client).Find(ctx, bson.M{"user_id": userID, "completed": true}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var entries []models.HistoryEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ImdbID)
+	}
+	return ids, nil
+}
+
+// EnsureHistoryRetention makes watch history expire HISTORY_RETENTION_DAYS
+// after it was last updated through a TTL index, and removes that index when
+// the variable is unset or 0 so history is kept until users clear it
+func EnsureHistoryRetention(ctx context.Context, client *mongo.Client) error {
+	days := 0
+	if value := os.Getenv("HISTORY_RETENTION_DAYS"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 0 {
+			return fmt.Errorf("invalid HISTORY_RETENTION_DAYS %q", value)
+		}
+	}
+	return database.EnsureTTLIndex(ctx, client, HistoryCollection, historyRetentionIndex, "last_watched_at", time.Duration(days)*24*time.Hour)
+}
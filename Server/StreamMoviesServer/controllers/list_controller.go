@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// collections holding the movies users saved, one document per (user_id, imdb_id)
+const (
+	WatchlistCollection  = "watchlist"
+	FavouritesCollection = "favourites"
+)
+
+// AddToList godoc
+// @Summary Save a movie
+// @Description Add a movie to the current user's watchlist or favourites. Adding a movie twice keeps the original date.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param imdb_id path string true "IMDb ID"
+// @Success 201 {object} models.SavedMovie
+// @Success 200 {object} models.SavedMovie
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/watchlist/{imdb_id} [post]
+// @Router /me/favourites/{imdb_id} [post]
+func AddToList(client *mongo.Client, list string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIDFromContext(c)
+		if err != nil {
+			c.Error(apperr.Unauthenticated(err))
+			return
+		}
+		imdbID, ok := imdbIDParam(c)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		count, err := database.OpenCollection("movies", client).CountDocuments(ctx, bson.M{"imdb_id": imdbID})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to save movie", err))
+			return
+		}
+		if count == 0 {
+			c.Error(apperr.NotFound("movie_not_found", "Movie not found"))
+			return
+		}
+
+		filter := bson.M{"user_id": userID, "imdb_id": imdbID}
+		update := bson.M{"$setOnInsert": bson.M{"user_id": userID, "imdb_id": imdbID, "added_at": time.Now()}}
+		listCollection := database.OpenCollection(list, client)
+		result, err := listCollection.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+		if err != nil {
+			c.Error(apperr.Internal("Failed to save movie", err))
+			return
+		}
+		var saved models.SavedMovie
+		if err := listCollection.FindOne(ctx, filter).Decode(&saved); err != nil {
+			c.Error(apperr.Internal("Failed to save movie", err))
+			return
+		}
+		status := http.StatusOK
+		if result.UpsertedCount > 0 {
+			status = http.StatusCreated
+			forgetUserRecommendations(ctx, userID)
+		}
+		c.JSON(status, saved)
+	}
+}
+
+// RemoveFromList godoc
+// @Summary Remove a saved movie
+// @Description Remove a movie from the current user's watchlist or favourites
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param imdb_id path string true "IMDb ID"
+// @Success 200 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/watchlist/{imdb_id} [delete]
+// @Router /me/favourites/{imdb_id} [delete]
+func RemoveFromList(client *mongo.Client, list string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIDFromContext(c)
+		if err != nil {
+			c.Error(apperr.Unauthenticated(err))
+			return
+		}
+
+		imdbID, ok := imdbIDParam(c)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		result, err := database.OpenCollection(list, client).DeleteOne(ctx, bson.M{"user_id": userID, "imdb_id": imdbID})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to remove movie", err))
+			return
+		}
+		if result.DeletedCount == 0 {
+			c.Error(apperr.NotFound("list_entry_not_found", "Movie is not on the "+list))
+			return
+		}
+		forgetUserRecommendations(ctx, userID)
+		c.JSON(http.StatusOK, gin.H{"message": "movie removed from " + list})
+	}
+}
+
+// GetList godoc
+// @Summary List saved movies
+// @Description Get a page of the current user's watchlist or favourites, newest first unless order=asc
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param order query string false "desc (default) or asc by date added"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Items per page, at most 100"
+// @Success 200 {object} models.Page[models.SavedMovie]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/watchlist [get]
+// @Router /me/favourites [get]
+func GetList(client *mongo.Client, list string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIDFromContext(c)
+		if err != nil {
+			c.Error(apperr.Unauthenticated(err))
+			return
+		}
+		order := -1
+		switch c.DefaultQuery("order", "desc") {
+		case "desc":
+		case "asc":
+			order = 1
+		default:
+			c.Error(apperr.InvalidParameter("order must be asc or desc"))
+			return
+		}
+		page, pageSize := getPagination(c)
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		listCollection := database.OpenCollection(list, client)
+		match := bson.M{"user_id": userID}
+		total, err := listCollection.CountDocuments(ctx, match)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to count "+list, err))
+			return
+		}
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: match}},
+			{{Key: "$sort", Value: bson.D{{Key: "added_at", Value: order}, {Key: "_id", Value: order}}}},
+			{{Key: "$skip", Value: (page - 1) * pageSize}},
+			{{Key: "$limit", Value: pageSize}},
+			{{Key: "$lookup", Value: bson.M{
+				"from":         "movies",
+				"localField":   "imdb_id",
+				"foreignField": "imdb_id",
+				"as":           "movie",
+			}}},
+			{{Key: "$unwind", Value: bson.M{"path": "$movie", "preserveNullAndEmptyArrays": true}}},
+		}
+		cursor, err := listCollection.Aggregate(ctx, pipeline)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to fetch "+list, err))
+			return
+		}
+		defer cursor.Close(ctx)
+
+		items := []models.SavedMovie{}
+		if err := cursor.All(ctx, &items); err != nil {
+			c.Error(apperr.Internal("Failed to decode "+list, err))
+			return
+		}
+		c.JSON(http.StatusOK, models.Page[models.SavedMovie]{Items: items, Page: page, PageSize: pageSize, Total: total})
+	}
+}
+
+// getSavedIDs returns the imdb ids the user saved to list
+func getSavedIDs(ctx context.Context, client *mongo.Client, userID, list string) ([]string, error) {
+	opts := options.Find().SetProjection(bson.M{"imdb_id": 1, "_id": 0})
+	cursor, err := database.OpenCollection(list, client).Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var saved []models.SavedMovie
+	if err := cursor.All(ctx, &saved); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(saved))
+	for _, movie := range saved {
+		ids = append(ids, movie.ImdbID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// The lookups below serve the GraphQL resolvers the same data the REST
+// handlers send, through the same cache. Errors are *apperr.Error.
+
+// Genres returns every genre
+func Genres(ctx context.Context, client *mongo.Client) ([]models.Genre, error) {
+	genres, err := cachedGenres(ctx, client)
+	if err != nil {
+		return nil, apperr.Internal("Failed to fetch genres", err)
+	}
+	return genres, nil
+}
+
+// GenreMovieCounts returns the number of movies per genre_id
+func GenreMovieCounts(ctx context.Context, client *mongo.Client) (map[int]int, error) {
+	counts, err := genreMovieCounts(ctx, client)
+	if err != nil {
+		return nil, apperr.Internal("Failed to count movies per genre", err)
+	}
+	return counts, nil
+}
+
+// Rankings returns the ranking scale
+func Rankings(ctx context.Context, client *mongo.Client) ([]models.Ranking, error) {
+	rankings, err := cachedRankings(ctx, client)
+	if err != nil {
+		return nil, apperr.Internal("Failed to fetch rankings", err)
+	}
+	return rankings, nil
+}
+
+// FindMovie returns the movie with imdbID, and false when there is none
+func FindMovie(ctx context.Context, client *mongo.Client, imdbID string) (models.Movie, bool, error) {
+	movie, err := cachedMovie(ctx, client, imdbID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return movie, false, nil
+	}
+	if err != nil {
+		return movie, false, apperr.Internal("Error fetching movie", err)
+	}
+	return movie, true, nil
+}
+
+// FindMovies returns a page of the movies matching filter in catalogue
+// order
+func FindMovies(ctx context.Context, client *mongo.Client, filter catalogue.MovieFilter, skip, limit int64) ([]models.Movie, error) {
+	opts := options.Find().SetProjection(movieProjection).SetSkip(skip).SetLimit(limit)
+	cursor, err := database.OpenCollection("movies", client).Find(ctx, filter.BSON(), opts)
+	if err != nil {
+		return nil, apperr.Internal("Failed to fetch movies", err)
+	}
+	var movies []models.Movie
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, apperr.Internal("Failed to decode movies", err)
+	}
+	return movies, nil
+}
+
+// MoviesByID returns the movies with the given imdb ids in one query, by
+// imdb id. Unknown ids are left out.
+func MoviesByID(ctx context.Context, client *mongo.Client, imdbIDs []string) (map[string]models.Movie, error) {
+	opts := options.Find().SetProjection(movieProjection)
+	cursor, err := database.OpenCollection("movies", client).Find(ctx, bson.M{"imdb_id": bson.M{"$in": imdbIDs}}, opts)
+	if err != nil {
+		return nil, apperr.Internal("Failed to fetch movies", err)
+	}
+	var movies []models.Movie
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, apperr.Internal("Failed to decode movies", err)
+	}
+	byID := make(map[string]models.Movie, len(movies))
+	for _, movie := range movies {
+		byID[movie.ImdbID] = movie
+	}
+	return byID, nil
+}
+
+// SavedMovieIDs returns the imdb ids on one of the user's lists,
+// WatchlistCollection or FavouritesCollection
+func SavedMovieIDs(ctx context.Context, client *mongo.Client, userID, list string) ([]string, error) {
+	ids, err := getSavedIDs(ctx, client, userID, list)
+	if err != nil {
+		return nil, apperr.Internal("Error fetching "+list, err)
+	}
+	return ids, nil
+}
@@ -4,63 +4,61 @@ package controllers
 import (
 	"context"
 	"errors"
-	"log"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/audit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/events"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/logging"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/metrics"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/recommender"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/tracing"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/utils"
 	"github.com/tmc/langchaingo/llms/openai"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// GetGenres godoc
-// @Summary Get all genres
-// @Description Get a list of all movie genres
-// @Tags genres
-// @Accept  json
-// @Produce  json
-// @Success 200 {array} models.Genre
-// @Failure 500 {object} models.ErrorResponse
-// @Router /genres [get]
-func GetGenres(client *mongo.Client) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(c, 100*time.Second)
-		defer cancel()
-
-		// var movieCollection *mongo.Collection = database.OpenCollection("movies")
-		genresCollection := database.OpenCollection("genres", client)
-		cursor, err := genresCollection.Find(ctx, bson.M{})
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch genres"})
-			return
-		}
-		defer cursor.Close(ctx)
-
-		var results []models.Genre
-		if err = cursor.All(ctx, &results); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode genres"})
-			return
-		}
-		c.JSON(http.StatusOK, results)
-	}
-}
+var logger = logging.For("controllers")
 
 // GetMovies godoc
 // @Summary Get all movies
-// @Description Get a list of all movies
+// @Description Get a list of all movies, optionally filtered
 // @Tags movies
 // @Accept  json
 // @Produce  json
+// @Param genre query string false "Genre names or ids, comma separated"
+// @Param ranking query string false "Ranking names, comma separated"
+// @Param max_ranking query int false "Highest ranking_value to include"
+// @Param q query string false "Title search"
+// @Param year query int false "Release year"
+// @Param year_from query int false "Earliest release year"
+// @Param year_to query int false "Latest release year"
+// @Param language query string false "ISO 639-1 original or spoken languages, comma separated"
+// @Param country query string false "ISO 3166-1 production countries, comma separated"
+// @Param certification query string false "Age certifications, comma separated"
+// @Param min_runtime query int false "Shortest runtime in minutes"
+// @Param max_runtime query int false "Longest runtime in minutes"
+// @Param person query string false "person_id credited in cast or crew"
+// @Param person_role query string false "cast, crew or a crew job such as Director"
+// @Param If-None-Match header string false "ETag of the list the client has"
+// @Param If-Modified-Since header string false "Last-Modified of the list the client has"
 // @Success 200 {array} models.Movie
+// @Success 304 "Not modified"
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /movies [get]
 func GetMovies(client *mongo.Client) gin.HandlerFunc {
@@ -68,17 +66,26 @@ func GetMovies(client *mongo.Client) gin.HandlerFunc {
 		ctx, cancel := context.WithTimeout(c, 100*time.Second)
 		defer cancel()
 
+		filter, err := catalogue.ParseMovieFilter(c.Request.URL.Query())
+		if err != nil {
+			c.Error(apperr.InvalidParameter(err.Error()))
+			return
+		}
+
 		movieCollection := database.OpenCollection("movies", client)
 		var movies []models.Movie
-		cursor, err := movieCollection.Find(ctx, bson.M{})
+		cursor, err := movieCollection.Find(ctx, filter.BSON(), options.Find().SetProjection(movieProjection))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Error(apperr.Internal("Failed to fetch movies", err))
 			return
 		}
 		defer cursor.Close(ctx)
 
 		if err = cursor.All(ctx, &movies); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode movies"})
+			c.Error(apperr.Internal("Failed to decode movies", err))
+			return
+		}
+		if notModified(c, moviesETag(movies), lastModified(movies)) {
 			return
 		}
 		c.JSON(http.StatusOK, movies)
@@ -92,8 +99,12 @@ func GetMovies(client *mongo.Client) gin.HandlerFunc {
 // @Accept  json
 // @Produce  json
 // @Param imdb_id path string true "IMDB ID"
+// @Param If-None-Match header string false "ETag of the movie the client has"
+// @Param If-Modified-Since header string false "Last-Modified of the movie the client has"
 // @Success 200 {object} models.Movie
+// @Success 304 "Not modified"
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /movie/{imdb_id} [get]
 func GetMovie(client *mongo.Client) gin.HandlerFunc {
@@ -101,61 +112,23 @@ func GetMovie(client *mongo.Client) gin.HandlerFunc {
 		ctx, cancel := context.WithTimeout(c, 100*time.Second)
 		defer cancel()
 
-		movieID := c.Param("imdb_id")
-		if movieID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid movie id"})
+		movieID, ok := imdbIDParam(c)
+		if !ok {
 			return
 		}
-		var movie models.Movie
-		movieCollection := database.OpenCollection("movies", client)
-		err := movieCollection.FindOne(ctx, bson.D{{Key: "imdb_id", Value: movieID}}).Decode(&movie)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get movies"})
+		movie, err := cachedMovie(ctx, client, movieID)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.Error(apperr.NotFound("movie_not_found", "Movie not found"))
 			return
 		}
-		c.JSON(http.StatusOK, movie)
-	}
-}
-
-// AddOrUpdateGenre godoc
-// @Summary Add or update a genre
-// @Description Add a new genre or update an existing one
-// @Tags genres
-// @Accept  json
-// @Produce  json
-// @Param genre body models.Genre true "Genre object"
-// @Success 201 {object} models.ErrorResponse
-// @Success 202 {object} models.ErrorResponse
-// @Failure 400 {object} models.ErrorResponse
-// @Router /genre [post]
-func AddOrUpdateGenre(client *mongo.Client) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(c, 100*time.Second)
-		defer cancel()
-
-		var genre models.Genre
-		genresCollection := database.OpenCollection("genres", client)
-		if err := c.ShouldBindJSON(&genre); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to get movies", err))
 			return
 		}
-		filter := bson.M{"genre_id": genre.GenreID}
-		update := bson.M{
-			"$set": bson.M{
-				"genre_name": genre.GenreName,
-			},
-		}
-		res := genresCollection.FindOneAndUpdate(ctx, filter, update)
-		if res.Err() == mongo.ErrNoDocuments {
-			_, err := genresCollection.InsertOne(ctx, genre)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-				return
-			}
-			c.JSON(http.StatusAccepted, gin.H{"message": "genre created"})
+		if notModified(c, movieETag(movie.Version), movie.UpdatedAt) {
 			return
 		}
-		c.JSON(http.StatusAccepted, gin.H{"message": "genre updated"})
+		c.JSON(http.StatusOK, movie)
 	}
 }
 
@@ -176,22 +149,30 @@ func AddMovie(client *mongo.Client) gin.HandlerFunc {
 
 		var movie models.Movie
 		movieCollection := database.OpenCollection("movies", client)
-		if err := c.ShouldBindJSON(&movie); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if !bindJSON(c, &movie) {
 			return
 		}
 		res := movieCollection.FindOne(ctx, bson.M{"imdb_id": movie.ImdbID})
 		if res.Err() == mongo.ErrNoDocuments {
+			if err := catalogue.PrepareMovie(ctx, client, &movie); err != nil {
+				c.Error(apperr.Internal("Failed to link cast and crew", err))
+				return
+			}
+			c.Set(audit.TargetContextKey, movie.ImdbID)
+			movie.Version, movie.UpdatedAt = 1, time.Now().UTC()
 			_, err := movieCollection.InsertOne(ctx, movie)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				c.Error(apperr.Internal("Failed to add movie", err))
 				return
 			}
+			forget(ctx, moviePrefix+movie.ImdbID)
+			refreshEmbedding(client, movie.ImdbID)
+			publish(ctx, events.MovieCreated, "", events.MovieChange{ImdbID: movie.ImdbID, Title: movie.Title, Version: movie.Version})
 		}
 		var insertedMovie models.Movie
 		err := movieCollection.FindOne(ctx, bson.D{{Key: "imdb_id", Value: movie.ImdbID}}).Decode(&insertedMovie)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.Error(apperr.Internal("Failed to fetch movie", err))
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"state": "success", "message": "posted data", "data": insertedMovie})
@@ -200,96 +181,164 @@ func AddMovie(client *mongo.Client) gin.HandlerFunc {
 
 // AdminReviewUpdate godoc
 // @Summary Update a movie review
-// @Description Update the admin review and ranking of a movie
+// @Description Update the admin review and ranking of a movie. If-Match is required, with the ETag of the movie the review was written against or "*" to overwrite whatever is stored. An answer from the language model outside the rankings scale leaves the movie unranked, a failed call changes nothing.
 // @Tags movies
 // @Accept  json
 // @Produce  json
 // @Param imdb_id path string true "IMDB ID"
+// @Param If-Match header string true "ETag of the movie the review was written against, or *"
 // @Param review body models.UpdateReview true "Review object"
 // @Success 200 {object} models.ErrorResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse
+// @Failure 428 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /movie/{imdb_id}/updatereview [patch]
 func AdminReviewUpdate(client *mongo.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, err := utils.GetRoleFromContext(c)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			c.Error(apperr.Unauthenticated(err))
 			return
 		}
 		if role != "ADMIN" && role != "USER" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User must be admin or user"})
+			c.Error(apperr.Forbidden("forbidden", "User must be admin or user"))
 			return
 		}
-		movieID := c.Param("imdb_id")
-		if movieID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Movie Id required"})
+		movieID, ok := imdbIDParam(c)
+		if !ok {
 			return
 		}
 		var req models.UpdateReview
-		var resp struct {
-			RankingName string `json:"ranking_name"`
-			AdminReview string `json:"admin_review"`
-		}
-		if err := c.ShouldBind(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		if !bindJSON(c, &req) {
 			return
 		}
-		sentiment, rankVal, err := GetReviewRanking(req.AdminReview, client, c)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting review ranking"})
-			return
-		}
-		filter := bson.M{"imdb_id": movieID}
-		update := bson.M{
-			"$set": bson.M{
-				"admin_review": req.AdminReview,
-				"ranking": bson.M{
-					"ranking_value": rankVal,
-					"ranking_name":  sentiment,
-				},
-			},
-		}
 		ctx, cancel := context.WithTimeout(c, 100*time.Second)
 		defer cancel()
 
-		movieCollection := database.OpenCollection("movies", client)
-		result, err := movieCollection.UpdateOne(ctx, filter, update)
+		userID, err := utils.GetUserIDFromContext(c)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating movie"})
+			c.Error(apperr.Unauthenticated(err))
 			return
 		}
-		if result.MatchedCount == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
+		updated, err := UpdateReview(ctx, client, userID, movieID, req.AdminReview, strings.TrimSpace(c.GetHeader("If-Match")))
+		if err != nil {
+			c.Error(err)
 			return
 		}
-		resp.AdminReview = req.AdminReview
-		resp.RankingName = sentiment
-		c.JSON(http.StatusOK, resp)
+		c.Header("ETag", movieETag(updated.Version))
+		c.JSON(http.StatusOK, updated)
 	}
 }
 
-func GetReviewRanking(adminReview string, client *mongo.Client, c *gin.Context) (string, int, error) {
-	rankings, err := GetRankings(client, c)
+// ReviewUpdate is a review as stored by UpdateReview with the ranking it got
+type ReviewUpdate struct {
+	RankingName string `json:"ranking_name"`
+	AdminReview string `json:"admin_review"`
+	Version     int64  `json:"-"`
+}
+
+// UpdateReview ranks review and stores both on the movie, telling reviewerID
+// the ranking once it is done. ifMatch is required and checked before paying
+// for the ranking, and again by the update which only applies to the version
+// the client saw, unless it is "*". Errors are *apperr.Error.
+func UpdateReview(ctx context.Context, client *mongo.Client, reviewerID, movieID, review, ifMatch string) (ReviewUpdate, error) {
+	if ifMatch == "" {
+		return ReviewUpdate{}, apperr.PreconditionRequired("precondition_required", `If-Match is required, send the ETag of the movie the review was written against or "*"`)
+	}
+	movieCollection := database.OpenCollection("movies", client)
+	var current struct {
+		Version int64 `bson:"version"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"version": 1})
+	err := movieCollection.FindOne(ctx, bson.M{"imdb_id": movieID}, opts).Decode(&current)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ReviewUpdate{}, apperr.NotFound("movie_not_found", "Movie not found")
+	}
+	if err != nil {
+		return ReviewUpdate{}, apperr.Internal("Error fetching movie", err)
+	}
+	if !etagMatches(ifMatch, movieETag(current.Version), true) {
+		return ReviewUpdate{}, movieChanged()
+	}
+
+	sentiment, rankVal, err := GetReviewRanking(review, client, ctx)
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) {
+		return ReviewUpdate{}, appErr
+	}
+	if err != nil {
+		return ReviewUpdate{}, apperr.Internal("Error getting review ranking", err)
+	}
+	filter := bson.M{"imdb_id": movieID}
+	if ifMatch != "*" {
+		filter["version"] = versionFilter(current.Version)
+	}
+	update := database.Versioned(bson.M{
+		"$set": bson.M{
+			"admin_review": review,
+			"ranking": bson.M{
+				"ranking_value": rankVal,
+				"ranking_name":  sentiment,
+			},
+		},
+	})
+	var updated struct {
+		Version int64 `bson:"version"`
+	}
+	updateOpts := options.FindOneAndUpdate().SetProjection(bson.M{"version": 1}).SetReturnDocument(options.After)
+	err = movieCollection.FindOneAndUpdate(ctx, filter, update, updateOpts).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, conditional := filter["version"]; conditional {
+			return ReviewUpdate{}, movieChanged()
+		}
+		return ReviewUpdate{}, apperr.NotFound("movie_not_found", "Movie not found")
+	}
+	if err != nil {
+		return ReviewUpdate{}, apperr.Internal("Error updating movie", err)
+	}
+	forgetMovie(ctx, movieID)
+	refreshEmbedding(client, movieID)
+	publish(ctx, events.MovieUpdated, "", events.MovieChange{ImdbID: movieID, Version: updated.Version})
+	publish(ctx, events.RankingCompleted, reviewerID, events.RankingResult{
+		ImdbID:       movieID,
+		RankingName:  sentiment,
+		RankingValue: rankVal,
+		Version:      updated.Version,
+	})
+	return ReviewUpdate{RankingName: sentiment, AdminReview: review, Version: updated.Version}, nil
+}
+
+func GetReviewRanking(adminReview string, client *mongo.Client, ctx context.Context) (sentiment string, rankVal int, err error) {
+	ctx, span := tracing.Start(ctx, "GetReviewRanking")
+	defer func() {
+		span.SetAttributes(attribute.String("ranking.name", sentiment), attribute.Int("ranking.value", rankVal))
+		tracing.End(span, err)
+	}()
+
+	rankings, err := cachedRankings(ctx, client)
 	if err != nil {
 		return "", 0, err
 	}
 	sentimentDelimited := ""
 	for _, ranking := range rankings {
-		if ranking.RankingValue != 999 {
+		if ranking.RankingValue != catalogue.UnrankedValue {
 			sentimentDelimited = sentimentDelimited + ranking.RankingName + ","
 		}
 		sentimentDelimited = strings.Trim(sentimentDelimited, ",")
 	}
 	err = godotenv.Load(".env")
 	if err != nil {
-		log.Println("Warning: .env file not found")
+		logger.DebugContext(ctx, ".env file not found")
 	}
 	OpenAiAPIKey := os.Getenv("OPENAI_API_KEY")
 	if OpenAiAPIKey == "" {
-		return "", 0, errors.New("could not get open ai api key")
+		return "", 0, apperr.Unavailable("llm_unavailable", "Language model is not configured", errors.New("OPENAI_API_KEY is not set"))
 	}
 	llm, err := openai.New(openai.WithToken(OpenAiAPIKey))
 	if err != nil {
@@ -297,92 +346,246 @@ func GetReviewRanking(adminReview string, client *mongo.Client, c *gin.Context)
 	}
 	basePromptTemplate := os.Getenv("BASE_PROMPT_TEMPLATE")
 	basePrompt := strings.Replace(basePromptTemplate, "{rankings}", sentimentDelimited, 1)
-	response, err := llm.Call(c, basePrompt+adminReview)
+	started := time.Now()
+	llmCtx, llmSpan := tracing.Start(ctx, "openai.call", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("gen_ai.system", "openai"), attribute.String("gen_ai.operation.name", "chat")))
+	response, err := llm.Call(llmCtx, basePrompt+adminReview)
+	tracing.End(llmSpan, err)
 	if err != nil {
-		return "", 0, nil
+		metrics.ReviewRanking(metrics.Error, time.Since(started))
+		logger.ErrorContext(ctx, "ranking review failed", "error", err)
+		return "", 0, apperr.Upstream("llm_failed", "Language model request failed", err)
 	}
-	rankVal := 0
-	for _, rankings := range rankings {
-		if rankings.RankingName == response {
-			rankVal = rankings.RankingValue
-			break
+	for _, ranking := range rankings {
+		if ranking.RankingName == response {
+			metrics.ReviewRanking(metrics.Success, time.Since(started))
+			return ranking.RankingName, ranking.RankingValue, nil
 		}
 	}
-	return response, rankVal, nil
-}
-
-func GetRankings(client *mongo.Client, c *gin.Context) ([]models.Ranking, error) {
-	var rankings []models.Ranking
-	ctx, cancel := context.WithTimeout(c, 100*time.Second)
-	defer cancel()
-
-	rankingCollection := database.OpenCollection("rankings", client)
-	cursor, err := rankingCollection.Find(ctx, bson.M{})
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	if err := cursor.All(ctx, &rankings); err != nil {
-		return nil, err
-	}
-	return rankings, nil
+	// an answer outside the scale leaves the movie unranked rather than
+	// storing a name no filter knows
+	metrics.ReviewRanking(metrics.Unmatched, time.Since(started))
+	logger.WarnContext(ctx, "ranking review matched no ranking", "response", response)
+	unranked := catalogue.Unranked(rankings)
+	return unranked.RankingName, unranked.RankingValue, nil
 }
 
 // GetRecommendedMovies godoc
 // @Summary Get recommended movies
-// @Description Get a list of recommended movies for the current user
+// @Description Get the movies the recommendation job picked for the current user, leaving out anything saved, rated or watched since. Before the job has run for them, the best ranked movies of their favourite genres and the genres of their favourite and finished movies are returned. Every movie carries its score and the reasons for it, strongest first.
+// @Description With debug=true admins get every candidate the recommender scores for the user, or for user_id, with its score parts instead.
 // @Tags movies
 // @Accept  json
 // @Produce  json
-// @Success 200 {array} models.Movie
+// @Param debug query bool false "Return all candidate scores (admin only)"
+// @Param user_id query string false "User to debug, defaults to the current user (admin only)"
+// @Success 200 {array} models.RecommendedMovie
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /recommendedmovies [get]
 func GetRecommendedMovies(client *mongo.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, err := utils.GetUserIDFromContext(c)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, err)
+			c.Error(apperr.Unauthenticated(err))
 			return
 		}
-		favouriteGenres, err := GetUsersFavouriteGenres(userID, client)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		err = godotenv.Load(".env")
-		if err != nil {
-			log.Println("Warning: .env file not found")
-		}
-		var recommendedMoviesLimitVal int64 = 5
-		recommendedMoviesLimitValStr := os.Getenv("RECOMMENDED_MOVIE_LIMIT")
-		if recommendedMoviesLimitValStr != "" {
-			recommendedMoviesLimitVal, _ = strconv.ParseInt(recommendedMoviesLimitValStr, 10, 64)
-		}
-		findOptions := options.Find()
-		findOptions.SetSort(bson.D{{Key: "ranking.ranking_value", Value: 1}})
-		findOptions.SetLimit(recommendedMoviesLimitVal)
-		filter := bson.M{"genre.genre_name": bson.M{"$in": favouriteGenres}}
 		ctx, cancel := context.WithTimeout(c, 100*time.Second)
 		defer cancel()
 
-		movieCollection := database.OpenCollection("movies", client)
-		cursor, err := movieCollection.Find(ctx, filter, findOptions)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching recommended movies"})
+		if c.Query("debug") == "true" {
+			role, err := utils.GetRoleFromContext(c)
+			if err != nil || role != "ADMIN" {
+				c.Error(apperr.Forbidden("admin_required", "Admin access required"))
+				return
+			}
+			if target := c.Query("user_id"); target != "" {
+				userID = target
+			}
+			explanation, err := recommender.NewEngine(client).Explain(ctx, userID)
+			if err != nil {
+				c.Error(apperr.Internal("Error scoring recommendations", err))
+				return
+			}
+			c.JSON(http.StatusOK, explanation)
 			return
 		}
-		defer cursor.Close(ctx)
 
-		var recommendedMovies []models.Movie
-		if err := cursor.All(ctx, &recommendedMovies); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		recommendedMovies, err := RecommendedMovies(ctx, client, userID, RecommendationLimit(ctx))
+		if err != nil {
+			c.Error(err)
 			return
 		}
 		c.JSON(http.StatusOK, recommendedMovies)
 	}
 }
 
+// RecommendationLimit is how many movies are recommended at once, set by
+// RECOMMENDED_MOVIE_LIMIT
+func RecommendationLimit(ctx context.Context) int64 {
+	err := godotenv.Load(".env")
+	if err != nil {
+		logger.DebugContext(ctx, ".env file not found")
+	}
+	var limit int64 = 5
+	if value := os.Getenv("RECOMMENDED_MOVIE_LIMIT"); value != "" {
+		limit, _ = strconv.ParseInt(value, 10, 64)
+	}
+	return limit
+}
+
+// RecommendedMovies returns up to limit movies for the user, from the
+// recommendation job or the fallback used before it ran for them. Errors are
+// *apperr.Error.
+func RecommendedMovies(ctx context.Context, client *mongo.Client, userID string, limit int64) ([]models.RecommendedMovie, error) {
+	recommendedMovies, err := cachedRecommendations(ctx, client, userID, limit)
+	if err != nil && !errors.Is(err, recommender.ErrNoRecommendations) {
+		return nil, apperr.Internal("Error fetching recommended movies", err)
+	}
+	if len(recommendedMovies) > 0 {
+		return recommendedMovies, nil
+	}
+
+	// nothing computed yet, for a new user or with the job turned off, so
+	// fall back to the best ranked movies of their favourite genres
+	favouriteGenres, err := GetUsersFavouriteGenres(userID, client)
+	if err != nil {
+		return nil, apperr.Internal("Error fetching favourite genres", err)
+	}
+	// saved and finished titles are already known to the user so they are
+	// left out, while their genres count like favourite genres
+	favourites, err := getSavedIDs(ctx, client, userID, FavouritesCollection)
+	if err != nil {
+		return nil, apperr.Internal("Error fetching favourites", err)
+	}
+	watchlist, err := getSavedIDs(ctx, client, userID, WatchlistCollection)
+	if err != nil {
+		return nil, apperr.Internal("Error fetching watchlist", err)
+	}
+	completed, err := getCompletedIDs(ctx, client, userID)
+	if err != nil {
+		return nil, apperr.Internal("Error fetching watch history", err)
+	}
+	// a movie watched to the end says as much about taste as a favourite
+	likedGenres, err := addFavouriteMovieGenres(ctx, client, append(favourites, completed...), nil)
+	if err != nil {
+		return nil, apperr.Internal("Error fetching favourites", err)
+	}
+
+	findOptions := options.Find().SetProjection(movieProjection)
+	findOptions.SetSort(bson.D{{Key: "ranking.ranking_value", Value: 1}})
+	findOptions.SetLimit(limit)
+	filter := bson.M{
+		"genre.genre_name": bson.M{"$in": append(slices.Clone(favouriteGenres), likedGenres...)},
+		"imdb_id":          bson.M{"$nin": append(append(favourites, watchlist...), completed...)},
+	}
+
+	movieCollection := database.OpenCollection("movies", client)
+	cursor, err := movieCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, apperr.Internal("Error fetching recommended movies", err)
+	}
+	defer cursor.Close(ctx)
+
+	var movies []models.Movie
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, apperr.Internal("Error fetching recommended movies", err)
+	}
+	rankings, err := cachedRankings(ctx, client)
+	if err != nil {
+		return nil, apperr.Internal("Error fetching rankings", err)
+	}
+	highlyRanked := catalogue.HighlyRanked(rankings)
+	cfg := recommender.ConfigFromEnv()
+	recommendedMovies = make([]models.RecommendedMovie, len(movies))
+	for i, movie := range movies {
+		score, reasons := recommender.ExplainMovie(cfg, highlyRanked, movie, favouriteGenres, likedGenres)
+		recommendedMovies[i] = models.RecommendedMovie{Movie: movie, Score: score, Reasons: reasons}
+	}
+	return recommendedMovies, nil
+}
+
+// storedRecommendations returns the movies picked for the user by the last
+// recommender run, best first, skipping what they saved, rated or watched since
+func storedRecommendations(ctx context.Context, client *mongo.Client, userID string, limit int64) ([]models.RecommendedMovie, error) {
+	recommendations, err := recommender.Load(ctx, client, userID)
+	if err != nil {
+		return nil, err
+	}
+	seen, err := recommender.SeenIDs(ctx, client, userID)
+	if err != nil {
+		return nil, err
+	}
+	var items []recommender.Item
+	var imdbIDs []string
+	for _, item := range recommendations.Items {
+		if int64(len(imdbIDs)) == limit {
+			break
+		}
+		if !seen[item.ImdbID] {
+			items = append(items, item)
+			imdbIDs = append(imdbIDs, item.ImdbID)
+		}
+	}
+	if len(imdbIDs) == 0 {
+		return nil, nil
+	}
+
+	opts := options.Find().SetProjection(movieProjection)
+	cursor, err := database.OpenCollection("movies", client).Find(ctx, bson.M{"imdb_id": bson.M{"$in": imdbIDs}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var found []models.Movie
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.Movie, len(found))
+	for _, movie := range found {
+		byID[movie.ImdbID] = movie
+	}
+	movies := make([]models.RecommendedMovie, 0, len(items))
+	for _, item := range items {
+		movie, ok := byID[item.ImdbID]
+		if !ok {
+			continue
+		}
+		reasons := item.Reasons
+		if reasons == nil {
+			// stored before reasons were recorded
+			reasons = []models.RecommendationReason{}
+		}
+		movies = append(movies, models.RecommendedMovie{Movie: movie, Score: item.Score, Reasons: reasons})
+	}
+	return movies, nil
+}
+
+// addFavouriteMovieGenres adds the genres of the given movies to genreNames,
+// skipping names already present
+func addFavouriteMovieGenres(ctx context.Context, client *mongo.Client, imdbIDs []string, genreNames []string) ([]string, error) {
+	if len(imdbIDs) == 0 {
+		return genreNames, nil
+	}
+	var names []string
+	err := database.OpenCollection("movies", client).
+		Distinct(ctx, "genre.genre_name", bson.M{"imdb_id": bson.M{"$in": imdbIDs}}).
+		Decode(&names)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(genreNames))
+	for _, name := range genreNames {
+		seen[name] = true
+	}
+	for _, name := range names {
+		if !seen[name] {
+			genreNames = append(genreNames, name)
+			seen[name] = true
+		}
+	}
+	return genreNames, nil
+}
+
 func GetUsersFavouriteGenres(userID string, client *mongo.Client) ([]string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 	defer cancel()
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// getPagination reads page and page_size from the query string, falling back
+// to the first page of defaultPageSize items for missing or invalid values
+func getPagination(c *gin.Context) (int64, int64) {
+	page, err := strconv.ParseInt(c.Query("page"), 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.ParseInt(c.Query("page_size"), 10, 64)
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return page, pageSize
+}
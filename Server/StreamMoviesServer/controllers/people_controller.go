@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/audit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// GetPeople godoc
+// @Summary List people
+// @Description Get a page of cast and crew members, optionally searched by name
+// @Tags people
+// @Accept  json
+// @Produce  json
+// @Param q query string false "Name search"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Items per page, at most 100"
+// @Success 200 {object} models.Page[models.Person]
+// @Failure 500 {object} models.ErrorResponse
+// @Router /people [get]
+func GetPeople(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		page, pageSize := getPagination(c)
+		filter := bson.M{}
+		if q := strings.TrimSpace(c.Query("q")); q != "" {
+			filter["name"] = bson.M{"$regex": regexp.QuoteMeta(q), "$options": "i"}
+		}
+
+		peopleCollection := database.OpenCollection("people", client)
+		total, err := peopleCollection.CountDocuments(ctx, filter)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to count people", err))
+			return
+		}
+		opts := options.Find().
+			SetSort(bson.D{{Key: "name", Value: 1}}).
+			SetSkip((page - 1) * pageSize).
+			SetLimit(pageSize)
+		cursor, err := peopleCollection.Find(ctx, filter, opts)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to fetch people", err))
+			return
+		}
+		defer cursor.Close(ctx)
+
+		people := []models.Person{}
+		if err := cursor.All(ctx, &people); err != nil {
+			c.Error(apperr.Internal("Failed to decode people", err))
+			return
+		}
+		c.JSON(http.StatusOK, models.Page[models.Person]{Items: people, Page: page, PageSize: pageSize, Total: total})
+	}
+}
+
+// GetPerson godoc
+// @Summary Get a person
+// @Description Get a cast or crew member with their filmography, newest first
+// @Tags people
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Person ID"
+// @Success 200 {object} models.PersonDetails
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /people/{id} [get]
+func GetPerson(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		personID := c.Param("id")
+		var person models.Person
+		err := database.OpenCollection("people", client).FindOne(ctx, bson.M{"person_id": personID}).Decode(&person)
+		if err == mongo.ErrNoDocuments {
+			c.Error(apperr.NotFound("person_not_found", "Person not found"))
+			return
+		}
+		if err != nil {
+			c.Error(apperr.Internal("Failed to get person", err))
+			return
+		}
+
+		filmography, err := getFilmography(ctx, client, personID)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to get filmography", err))
+			return
+		}
+		c.JSON(http.StatusOK, models.PersonDetails{Person: person, Filmography: filmography})
+	}
+}
+
+func getFilmography(ctx context.Context, client *mongo.Client, personID string) ([]models.FilmographyCredit, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"cast.person_id": personID},
+		bson.M{"crew.person_id": personID},
+	}}
+	projection := bson.M{
+		"imdb_id": 1, "title": 1, "poster_path": 1, "release_year": 1, "ranking": 1, "cast": 1, "crew": 1,
+	}
+	cursor, err := database.OpenCollection("movies", client).Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	credits := []models.FilmographyCredit{}
+	for cursor.Next(ctx) {
+		var movie models.Movie
+		if err := cursor.Decode(&movie); err != nil {
+			return nil, err
+		}
+		base := models.FilmographyCredit{
+			ImdbID:      movie.ImdbID,
+			Title:       movie.Title,
+			PosterPath:  movie.PosterPath,
+			ReleaseYear: movie.ReleaseYear,
+			Ranking:     movie.Ranking,
+		}
+		for _, member := range movie.Cast {
+			if member.PersonID == personID {
+				credit := base
+				credit.Role = "cast"
+				credit.Character = member.Character
+				credit.Order = member.Order
+				credits = append(credits, credit)
+			}
+		}
+		for _, member := range movie.Crew {
+			if member.PersonID == personID {
+				credit := base
+				credit.Role = "crew"
+				credit.Job = member.Job
+				credit.Department = member.Department
+				credits = append(credits, credit)
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(credits, func(i, j int) bool {
+		if credits[i].ReleaseYear != credits[j].ReleaseYear {
+			return credits[i].ReleaseYear > credits[j].ReleaseYear
+		}
+		return credits[i].Title < credits[j].Title
+	})
+	return credits, nil
+}
+
+// AddPerson godoc
+// @Summary Add a person
+// @Description Add a cast or crew member. Admin only.
+// @Tags people
+// @Accept  json
+// @Produce  json
+// @Param person body models.Person true "Person object"
+// @Success 201 {object} models.Person
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /people [post]
+func AddPerson(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var person models.Person
+		if !bindJSON(c, &person) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		peopleCollection := database.OpenCollection("people", client)
+		if person.TMDBID != 0 {
+			count, err := peopleCollection.CountDocuments(ctx, bson.M{"tmdb_id": person.TMDBID})
+			if err != nil {
+				c.Error(apperr.Internal("Failed to add person", err))
+				return
+			}
+			if count > 0 {
+				c.Error(apperr.Conflict("person_exists", "Person with this tmdb_id already exists"))
+				return
+			}
+		}
+
+		person.ID = bson.ObjectID{}
+		person.PersonID = bson.NewObjectID().Hex()
+		c.Set(audit.TargetContextKey, person.PersonID)
+		person.CreatedAt = time.Now()
+		person.UpdatedAt = time.Now()
+		_, err := peopleCollection.InsertOne(ctx, person)
+		if mongo.IsDuplicateKeyError(err) {
+			// a concurrent request added the same tmdb_id after our check
+			c.Error(apperr.Conflict("person_exists", "Person with this tmdb_id already exists"))
+			return
+		}
+		if err != nil {
+			c.Error(apperr.Internal("Failed to add person", err))
+			return
+		}
+		c.JSON(http.StatusCreated, person)
+	}
+}
+
+// UpdatePerson godoc
+// @Summary Update a person
+// @Description Update a cast or crew member, the new name is copied into every movie crediting them. Admin only.
+// @Tags people
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Person ID"
+// @Param person body models.Person true "Person object"
+// @Success 200 {object} models.Person
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /people/{id} [put]
+func UpdatePerson(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		personID := c.Param("id")
+		var person models.Person
+		if !bindJSON(c, &person) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		update := bson.M{"$set": bson.M{
+			"name":                 person.Name,
+			"biography":            person.Biography,
+			"birthday":             person.Birthday,
+			"place_of_birth":       person.PlaceOfBirth,
+			"known_for_department": person.KnownForDepartment,
+			"profile_path":         person.ProfilePath,
+			"update_at":            time.Now(),
+		}}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		var updated models.Person
+		err := database.OpenCollection("people", client).
+			FindOneAndUpdate(ctx, bson.M{"person_id": personID}, update, opts).
+			Decode(&updated)
+		if err == mongo.ErrNoDocuments {
+			c.Error(apperr.NotFound("person_not_found", "Person not found"))
+			return
+		}
+		if err != nil {
+			c.Error(apperr.Internal("Failed to update person", err))
+			return
+		}
+
+		err = syncCredits(ctx, client, updated)
+		forgetMovies(ctx)
+		if err != nil {
+			c.Error(apperr.Internal("Person updated but movie credits could not be refreshed", err))
+			return
+		}
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+// syncCredits refreshes the copies of a person's name and picture kept in
+// the cast and crew of their movies
+func syncCredits(ctx context.Context, client *mongo.Client, person models.Person) error {
+	movieCollection := database.OpenCollection("movies", client)
+	for _, field := range []string{"cast", "crew"} {
+		update := database.Versioned(bson.M{"$set": bson.M{
+			field + ".$[credit].name":         person.Name,
+			field + ".$[credit].profile_path": person.ProfilePath,
+		}})
+		opts := options.UpdateMany().SetArrayFilters([]any{bson.M{"credit.person_id": person.PersonID}})
+		if _, err := movieCollection.UpdateMany(ctx, bson.M{field + ".person_id": person.PersonID}, update, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeletePerson godoc
+// @Summary Delete a person
+// @Description Delete a cast or crew member who is not credited in any movie. Admin only.
+// @Tags people
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Person ID"
+// @Success 200 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /people/{id} [delete]
+func DeletePerson(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		personID := c.Param("id")
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		credited, err := database.OpenCollection("movies", client).CountDocuments(ctx, bson.M{"$or": bson.A{
+			bson.M{"cast.person_id": personID},
+			bson.M{"crew.person_id": personID},
+		}})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to delete person", err))
+			return
+		}
+		if credited > 0 {
+			c.Error(apperr.Conflict("person_in_use", "Person is still credited in movies").With("movies", credited))
+			return
+		}
+
+		result, err := database.OpenCollection("people", client).DeleteOne(ctx, bson.M{"person_id": personID})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to delete person", err))
+			return
+		}
+		if result.DeletedCount == 0 {
+			c.Error(apperr.NotFound("person_not_found", "Person not found"))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "person deleted"})
+	}
+}
@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/audit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/events"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// GetRankings godoc
+// @Summary Get the rankings scale
+// @Description Get every ranking an admin review can map to, best first. The value 999 is reserved for movies that are not ranked yet.
+// @Tags rankings
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} models.Ranking
+// @Failure 500 {object} models.ErrorResponse
+// @Router /rankings [get]
+func GetRankings(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		rankings, err := cachedRankings(ctx, client)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to fetch rankings", err))
+			return
+		}
+		if rankings == nil {
+			rankings = []models.Ranking{}
+		}
+		c.JSON(http.StatusOK, rankings)
+	}
+}
+
+// AddRanking godoc
+// @Summary Add a ranking
+// @Description Add a ranking to the scale. Names and values must be unique and 999 is reserved. Admin only.
+// @Tags rankings
+// @Accept  json
+// @Produce  json
+// @Param ranking body models.Ranking true "Ranking"
+// @Success 201 {object} models.Ranking
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /rankings [post]
+func AddRanking(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ranking, ok := bindRanking(c)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		if !rankingAvailable(ctx, c, client, ranking, 0) {
+			return
+		}
+		c.Set(audit.TargetContextKey, ranking.RankingValue)
+		_, err := database.OpenCollection("rankings", client).InsertOne(ctx, ranking)
+		if mongo.IsDuplicateKeyError(err) {
+			c.Error(rankingTaken())
+			return
+		}
+		if err != nil {
+			c.Error(apperr.Internal("Failed to add ranking", err))
+			return
+		}
+		forget(ctx, rankingsKey)
+		c.JSON(http.StatusCreated, ranking)
+	}
+}
+
+// UpdateRanking godoc
+// @Summary Update a ranking
+// @Description Change the name or value of a ranking and re-rank the movies that have it, in one transaction. The reserved 999 ranking cannot be changed. Admin only.
+// @Tags rankings
+// @Accept  json
+// @Produce  json
+// @Param value path int true "Current ranking value"
+// @Param ranking body models.Ranking true "Ranking"
+// @Success 200 {object} models.Ranking
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /rankings/{value} [put]
+func UpdateRanking(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := rankingValueParam(c)
+		if !ok {
+			return
+		}
+		ranking, ok := bindRanking(c)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		if !rankingExists(ctx, c, client, value) || !rankingAvailable(ctx, c, client, ranking, value) {
+			return
+		}
+		// the ranking is found under its new value afterwards
+		c.Set(audit.TargetContextKey, ranking.RankingValue)
+		var moved int64
+		err := inTransaction(ctx, client, func(ctx context.Context) error {
+			_, err := database.OpenCollection("rankings", client).ReplaceOne(ctx, bson.M{"ranking_value": value}, ranking)
+			if err != nil {
+				return err
+			}
+			result, err := database.OpenCollection("movies", client).UpdateMany(ctx,
+				bson.M{"ranking.ranking_value": value},
+				database.Versioned(bson.M{"$set": bson.M{"ranking": ranking}}))
+			if err != nil {
+				return err
+			}
+			moved = result.ModifiedCount
+			return nil
+		})
+		if mongo.IsDuplicateKeyError(err) {
+			c.Error(rankingTaken())
+			return
+		}
+		if err != nil {
+			c.Error(apperr.Internal("Failed to update ranking", err))
+			return
+		}
+		forget(ctx, rankingsKey)
+		forgetMovies(ctx)
+		if moved > 0 {
+			publish(ctx, events.CatalogueReranked, "", events.RerankChange{Action: "updated", PreviousValue: value, RankingName: ranking.RankingName, RankingValue: ranking.RankingValue, Movies: moved})
+		}
+		c.JSON(http.StatusOK, ranking)
+	}
+}
+
+// DeleteRanking godoc
+// @Summary Delete a ranking
+// @Description Delete a ranking from the scale, the movies that had it become unranked in the same transaction. The reserved 999 ranking cannot be deleted. Admin only.
+// @Tags rankings
+// @Accept  json
+// @Produce  json
+// @Param value path int true "Ranking value"
+// @Success 200 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /rankings/{value} [delete]
+func DeleteRanking(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := rankingValueParam(c)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		if !rankingExists(ctx, c, client, value) {
+			return
+		}
+		scale, err := catalogue.LoadRankings(ctx, client)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to delete ranking", err))
+			return
+		}
+		var moved int64
+		err = inTransaction(ctx, client, func(ctx context.Context) error {
+			if _, err := database.OpenCollection("rankings", client).DeleteOne(ctx, bson.M{"ranking_value": value}); err != nil {
+				return err
+			}
+			result, err := database.OpenCollection("movies", client).UpdateMany(ctx,
+				bson.M{"ranking.ranking_value": value},
+				database.Versioned(bson.M{"$set": bson.M{"ranking": catalogue.Unranked(scale)}}))
+			if err != nil {
+				return err
+			}
+			moved = result.ModifiedCount
+			return nil
+		})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to delete ranking", err))
+			return
+		}
+		forget(ctx, rankingsKey)
+		forgetMovies(ctx)
+		if moved > 0 {
+			unranked := catalogue.Unranked(scale)
+			publish(ctx, events.CatalogueReranked, "", events.RerankChange{Action: "deleted", PreviousValue: value, RankingName: unranked.RankingName, RankingValue: unranked.RankingValue, Movies: moved})
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "ranking deleted", "movies_unranked": moved})
+	}
+}
+
+// RerankMovies godoc
+// @Summary Re-rank movies
+// @Description Bring the ranking copied into every movie back in line with the scale, after imports or edits made directly in the database. Movies whose ranking is no longer on the scale become unranked. Admin only.
+// @Tags rankings
+// @Accept  json
+// @Produce  json
+// @Param dry_run query bool false "Only count the movies that would change"
+// @Success 200 {object} map[string]int
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/rerank [post]
+func RerankMovies(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, 10*time.Minute)
+		defer cancel()
+
+		dryRun := c.Query("dry_run") == "true"
+		changed, err := catalogue.Rerank(ctx, client, dryRun)
+		// a failed re-rank may have written some batches already
+		if changed > 0 && !dryRun {
+			forgetMovies(ctx)
+			publish(ctx, events.CatalogueReranked, "", events.RerankChange{Action: "rerank", Movies: int64(changed)})
+		}
+		if err != nil {
+			c.Error(apperr.Internal("Failed to re-rank movies", err).With("changed", changed))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"changed": changed})
+	}
+}
+
+func bindRanking(c *gin.Context) (models.Ranking, bool) {
+	var ranking models.Ranking
+	if !bindJSON(c, &ranking) {
+		return ranking, false
+	}
+	if ranking.RankingValue == catalogue.UnrankedValue {
+		c.Error(apperr.BadRequest("ranking_reserved", "Ranking value 999 is reserved for unranked movies"))
+		return ranking, false
+	}
+	return ranking, true
+}
+
+func rankingValueParam(c *gin.Context) (int, bool) {
+	value, err := strconv.Atoi(c.Param("value"))
+	if err != nil {
+		c.Error(apperr.InvalidParameter("Invalid ranking value"))
+		return 0, false
+	}
+	if value == catalogue.UnrankedValue {
+		c.Error(apperr.BadRequest("ranking_reserved", "The unranked ranking cannot be changed"))
+		return 0, false
+	}
+	return value, true
+}
+
+func rankingExists(ctx context.Context, c *gin.Context, client *mongo.Client, value int) bool {
+	count, err := database.OpenCollection("rankings", client).CountDocuments(ctx, bson.M{"ranking_value": value})
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch ranking", err))
+		return false
+	}
+	if count == 0 {
+		c.Error(apperr.NotFound("ranking_not_found", "Ranking not found"))
+		return false
+	}
+	return true
+}
+
+// rankingAvailable reports whether no ranking other than the one at current
+// (0 for none) has the name, ignoring case, or the value
+func rankingAvailable(ctx context.Context, c *gin.Context, client *mongo.Client, ranking models.Ranking, current int) bool {
+	filter := bson.M{
+		"ranking_value": bson.M{"$ne": current},
+		"$or": bson.A{
+			bson.M{"ranking_value": ranking.RankingValue},
+			bson.M{"ranking_name": bson.M{"$regex": "^" + regexp.QuoteMeta(ranking.RankingName) + "$", "$options": "i"}},
+		},
+	}
+	taken, err := database.OpenCollection("rankings", client).CountDocuments(ctx, filter)
+	if err != nil {
+		c.Error(apperr.Internal("Failed to fetch rankings", err))
+		return false
+	}
+	if taken > 0 {
+		c.Error(rankingTaken())
+		return false
+	}
+	return true
+}
+
+// rankingTaken is also what the unique indexes on rankings report, for
+// writes racing past rankingAvailable
+func rankingTaken() *apperr.Error {
+	return apperr.Conflict("ranking_exists", "Another ranking already has this name or value")
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const RatingsCollection = "ratings"
+
+// RateMovie godoc
+// @Summary Rate a movie
+// @Description Set the current user's rating of a movie from 1 to 5, replacing an earlier one
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param imdb_id path string true "IMDb ID"
+// @Param rating body models.Rating true "Rating, only the rating field is read"
+// @Success 200 {object} models.Rating
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/ratings/{imdb_id} [put]
+func RateMovie(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIDFromContext(c)
+		if err != nil {
+			c.Error(apperr.Unauthenticated(err))
+			return
+		}
+		var rating models.Rating
+		if !bindJSON(c, &rating) {
+			return
+		}
+		rating.UserID = userID
+		imdbID, ok := imdbIDParam(c)
+		if !ok {
+			return
+		}
+		rating.ImdbID = imdbID
+		rating.RatedAt = time.Now()
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		count, err := database.OpenCollection("movies", client).CountDocuments(ctx, bson.M{"imdb_id": rating.ImdbID})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to rate movie", err))
+			return
+		}
+		if count == 0 {
+			c.Error(apperr.NotFound("movie_not_found", "Movie not found"))
+			return
+		}
+
+		filter := bson.M{"user_id": userID, "imdb_id": rating.ImdbID}
+		opts := options.Replace().SetUpsert(true)
+		if _, err := database.OpenCollection(RatingsCollection, client).ReplaceOne(ctx, filter, rating, opts); err != nil {
+			c.Error(apperr.Internal("Failed to rate movie", err))
+			return
+		}
+		forgetUserRecommendations(ctx, userID)
+		c.JSON(http.StatusOK, rating)
+	}
+}
+
+// GetRatings godoc
+// @Summary List ratings
+// @Description Get a page of the current user's ratings, most recent first
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Items per page, at most 100"
+// @Success 200 {object} models.Page[models.Rating]
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/ratings [get]
+func GetRatings(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIDFromContext(c)
+		if err != nil {
+			c.Error(apperr.Unauthenticated(err))
+			return
+		}
+		page, pageSize := getPagination(c)
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		ratingsCollection := database.OpenCollection(RatingsCollection, client)
+		filter := bson.M{"user_id": userID}
+		total, err := ratingsCollection.CountDocuments(ctx, filter)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to count ratings", err))
+			return
+		}
+		opts := options.Find().
+			SetSort(bson.D{{Key: "rated_at", Value: -1}, {Key: "_id", Value: -1}}).
+			SetSkip((page - 1) * pageSize).
+			SetLimit(pageSize)
+		cursor, err := ratingsCollection.Find(ctx, filter, opts)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to fetch ratings", err))
+			return
+		}
+		defer cursor.Close(ctx)
+
+		ratings := []models.Rating{}
+		if err := cursor.All(ctx, &ratings); err != nil {
+			c.Error(apperr.Internal("Failed to decode ratings", err))
+			return
+		}
+		c.JSON(http.StatusOK, models.Page[models.Rating]{Items: ratings, Page: page, PageSize: pageSize, Total: total})
+	}
+}
+
+// DeleteRating godoc
+// @Summary Delete a rating
+// @Description Remove the current user's rating of a movie
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param imdb_id path string true "IMDb ID"
+// @Success 200 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/ratings/{imdb_id} [delete]
+func DeleteRating(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIDFromContext(c)
+		if err != nil {
+			c.Error(apperr.Unauthenticated(err))
+			return
+		}
+
+		imdbID, ok := imdbIDParam(c)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		result, err := database.OpenCollection(RatingsCollection, client).DeleteOne(ctx, bson.M{"user_id": userID, "imdb_id": imdbID})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to delete rating", err))
+			return
+		}
+		if result.DeletedCount == 0 {
+			c.Error(apperr.NotFound("rating_not_found", "Movie is not rated"))
+			return
+		}
+		forgetUserRecommendations(ctx, userID)
+		c.JSON(http.StatusOK, gin.H{"message": "rating deleted"})
+	}
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/embeddings"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	defaultSimilarLimit = 10
+	maxSimilarLimit     = 50
+)
+
+// movieProjection leaves the embedding vector out of movie listings
+var movieProjection = bson.M{"embedding": 0, "embedding_model": 0, "embedding_hash": 0}
+
+// GetSimilarMovies godoc
+// @Summary Get similar movies
+// @Description Get the movies whose title, genres, overview and admin review are closest to a movie's, by embedding similarity
+// @Tags movies
+// @Accept  json
+// @Produce  json
+// @Param imdb_id path string true "IMDB ID"
+// @Param limit query int false "Number of movies, at most 50"
+// @Success 200 {array} models.SimilarMovie
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /movie/{imdb_id}/similar [get]
+func GetSimilarMovies(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := defaultSimilarLimit
+		if value := c.Query("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				c.Error(apperr.InvalidParameter("limit must be a positive number"))
+				return
+			}
+			limit = min(parsed, maxSimilarLimit)
+		}
+		store, err := embeddings.NewStoreFromEnv(client)
+		if err != nil {
+			logger.WarnContext(c, "embedder unavailable", "error", err)
+			c.Error(apperr.Unavailable("embeddings_unavailable", "Embeddings are not configured", err))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		imdbID, ok := imdbIDParam(c)
+		if !ok {
+			return
+		}
+		vector, err := store.EmbedMovie(ctx, imdbID)
+		if errors.Is(err, embeddings.ErrMovieNotFound) {
+			c.Error(apperr.NotFound("movie_not_found", "Movie not found"))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "embedding movie failed", "imdb_id", imdbID, "error", err)
+			c.Error(apperr.Internal("Failed to embed movie", err))
+			return
+		}
+		matches, err := store.Similar(ctx, vector, imdbID, limit)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to search similar movies", err))
+			return
+		}
+
+		imdbIDs := make([]string, len(matches))
+		for i, match := range matches {
+			imdbIDs[i] = match.ImdbID
+		}
+		opts := options.Find().SetProjection(movieProjection)
+		cursor, err := database.OpenCollection("movies", client).Find(ctx, bson.M{"imdb_id": bson.M{"$in": imdbIDs}}, opts)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to fetch similar movies", err))
+			return
+		}
+		var movies []models.Movie
+		if err := cursor.All(ctx, &movies); err != nil {
+			c.Error(apperr.Internal("Failed to decode similar movies", err))
+			return
+		}
+		byID := make(map[string]models.Movie, len(movies))
+		for _, movie := range movies {
+			byID[movie.ImdbID] = movie
+		}
+		similar := make([]models.SimilarMovie, 0, len(matches))
+		for _, match := range matches {
+			if movie, ok := byID[match.ImdbID]; ok {
+				similar = append(similar, models.SimilarMovie{Movie: movie, Similarity: match.Similarity})
+			}
+		}
+		c.JSON(http.StatusOK, similar)
+	}
+}
+
+// refreshEmbedding re-embeds a movie after its text changed. It runs in the
+// background so a slow embedding provider does not hold up the response.
+func refreshEmbedding(client *mongo.Client, imdbID string) {
+	store, err := embeddings.NewStoreFromEnv(client)
+	if err != nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		store.Refresh(ctx, imdbID)
+	}()
+}
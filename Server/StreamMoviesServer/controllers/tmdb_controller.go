@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/events"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/tmdb"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var bulkEnrichmentRunning atomic.Bool
+
+func newEnricher(client *mongo.Client) (*tmdb.Enricher, error) {
+	tmdbClient, err := tmdb.NewClientFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &tmdb.Enricher{Client: tmdbClient, Mongo: client}, nil
+}
+
+// EnrichMovie godoc
+// @Summary Enrich a movie from TMDB
+// @Description Fill in overview, release date, runtime, cast, crew and genres from TMDB. Admin only.
+// @Tags movies
+// @Accept  json
+// @Produce  json
+// @Param imdb_id path string true "IMDB ID"
+// @Success 200 {object} tmdb.EnrichResult
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /movie/{imdb_id}/enrich [post]
+func EnrichMovie(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		movieID, ok := imdbIDParam(c)
+		if !ok {
+			return
+		}
+		enricher, err := newEnricher(client)
+		if err != nil {
+			logger.WarnContext(c, "tmdb client unavailable", "error", err)
+			c.Error(apperr.Unavailable("tmdb_unavailable", "TMDB is not configured", err))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		result, err := enricher.Enrich(ctx, movieID)
+		forgetMovie(ctx, movieID)
+		switch {
+		case errors.Is(err, tmdb.ErrMovieNotFound):
+			c.Error(apperr.NotFound("movie_not_found", "Movie not found"))
+			return
+		case errors.Is(err, tmdb.ErrNotFound):
+			c.Error(apperr.NotFound("tmdb_movie_not_found", "Movie not found on TMDB"))
+			return
+		case err != nil:
+			c.Error(apperr.Upstream("tmdb_failed", "Failed to enrich movie", err))
+			return
+		}
+		publish(ctx, events.MovieUpdated, "", events.MovieChange{ImdbID: movieID})
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// EnrichAllMovies godoc
+// @Summary Enrich all movies from TMDB
+// @Description Start a background job enriching every movie, or only never enriched ones. Admin only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param missing_only query bool false "Only movies without TMDB data"
+// @Success 202 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/enrich [post]
+func EnrichAllMovies(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		enricher, err := newEnricher(client)
+		if err != nil {
+			logger.WarnContext(c, "tmdb client unavailable", "error", err)
+			c.Error(apperr.Unavailable("tmdb_unavailable", "TMDB is not configured", err))
+			return
+		}
+		if !bulkEnrichmentRunning.CompareAndSwap(false, true) {
+			c.Error(apperr.Conflict("enrichment_running", "Enrichment is already running"))
+			return
+		}
+		onlyMissing := c.Query("missing_only") == "true"
+
+		go func() {
+			defer bulkEnrichmentRunning.Store(false)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+			defer cancel()
+
+			enriched, failed, err := enricher.EnrichAll(ctx, onlyMissing, func(imdbID string, err error) {
+				if err != nil {
+					logger.WarnContext(ctx, "enriching movie failed", "imdb_id", imdbID, "error", err)
+				}
+			})
+			if err != nil {
+				logger.ErrorContext(ctx, "bulk enrichment stopped", "error", err)
+			}
+			forgetMovies(ctx)
+			logger.InfoContext(ctx, "bulk enrichment finished", "enriched", enriched, "failed", failed)
+		}()
+
+		c.JSON(http.StatusAccepted, gin.H{"message": "Enrichment started"})
+	}
+}
@@ -7,15 +7,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/events"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/metrics"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/middlewares"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/tracing"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/utils"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -42,7 +45,7 @@ func GoogleLogin(client *mongo.Client) gin.HandlerFunc {
 		b := make([]byte, 16)
 		_, err := rand.Read(b)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Error(apperr.Internal("Failed to start sign in", err))
 			return
 		}
 		state = base64.URLEncoding.EncodeToString(b)
@@ -60,28 +63,29 @@ func GoogleLogin(client *mongo.Client) gin.HandlerFunc {
 
 func GoogleCallback(client *mongo.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		defer observeAuth(c, "google")
 		oauthState, _ := c.Cookie("oauthstate")
 		if c.Query("state") != oauthState {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid state"})
+			c.Error(apperr.BadRequest("invalid_oauth_state", "Invalid OAuth state"))
 			return
 		}
 		code := c.Query("code")
 		token, err := googleOauthConfig.Exchange(context.Background(), code)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to exchange token", "details": err.Error()})
+			c.Error(apperr.Internal("Failed to exchange token", err))
 			return
 		}
 
 		response, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + token.AccessToken)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info", "details": err.Error()})
+			c.Error(apperr.Internal("Failed to get user info", err))
 			return
 		}
 		defer response.Body.Close()
 
 		contents, err := io.ReadAll(response.Body)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read user info", "details": err.Error()})
+			c.Error(apperr.Internal("Failed to read user info", err))
 			return
 		}
 		var userInfo struct {
@@ -90,7 +94,7 @@ func GoogleCallback(client *mongo.Client) gin.HandlerFunc {
 			LastName  string `json:"family_name"`
 		}
 		if err := json.Unmarshal(contents, &userInfo); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse user info", "details": err.Error()})
+			c.Error(apperr.Internal("Failed to parse user info", err))
 			return
 		}
 		ctx, cancel := context.WithTimeout(c, 100*time.Second)
@@ -115,23 +119,23 @@ func GoogleCallback(client *mongo.Client) gin.HandlerFunc {
 
 			_, err := usersCollection.InsertOne(ctx, user)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+				c.Error(apperr.Internal("Failed to create user", err))
 				return
 			}
 		} else if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error", "details": err.Error()})
+			c.Error(apperr.Internal("Database error", err))
 			return
 		}
 		// User exists or was just created, generate tokens
 		appToken, refreshToken, err := utils.GenerateAllTokens(user.Email, user.FirstName, user.LastName, user.Role, user.UserID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+			c.Error(apperr.Internal("Failed to generate tokens", err))
 			return
 		}
 
 		err = utils.UpdateAllTokens(user.UserID, appToken, refreshToken, client)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tokens"})
+			c.Error(apperr.Internal("Failed to update tokens", err))
 			return
 		}
 
@@ -158,6 +162,12 @@ func GoogleCallback(client *mongo.Client) gin.HandlerFunc {
 	}
 }
 
+// observeAuth counts the attempt to sign in with method by the status the
+// handler answered with
+func observeAuth(c *gin.Context, method string) {
+	metrics.AuthAttempt(method, metrics.StatusOutcome(middlewares.ResponseStatus(c)))
+}
+
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -168,7 +178,7 @@ func HashPassword(password string) (string, error) {
 
 // RegisterUser godoc
 // @Summary Register a new user
-// @Description Register a new user with email and password
+// @Description Register a new user with email and password. Everyone registers as USER, a role sent along is ignored.
 // @Tags users
 // @Accept  json
 // @Produce  json
@@ -176,6 +186,7 @@ func HashPassword(password string) (string, error) {
 // @Success 201 {object} primitive.ObjectID
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 409 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /register [post]
 func RegisterUser(client *mongo.Client) gin.HandlerFunc {
@@ -183,17 +194,12 @@ func RegisterUser(client *mongo.Client) gin.HandlerFunc {
 		var user models.User
 		usersCollection := database.OpenCollection("users", client)
 
-		if err := c.ShouldBindJSON(&user); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input data"})
-		}
-		validate := validator.New()
-		if err := validate.Struct(user); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		if !bindJSON(c, &user) {
 			return
 		}
 		hashedPassword, err := HashPassword(user.Password)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid credentials", "details": err.Error()})
+			c.Error(apperr.Internal("invalid credentials", err))
 			return
 
 		}
@@ -201,11 +207,11 @@ func RegisterUser(client *mongo.Client) gin.HandlerFunc {
 		defer cancel()
 		count, err := usersCollection.CountDocuments(ctx, bson.M{"email": user.Email})
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "User already exists"})
+			c.Error(apperr.Internal("Failed to register user", err))
 			return
 		}
 		if count > 0 {
-			c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
+			c.Error(apperr.Conflict("user_exists", "User already exists"))
 			return
 		}
 		user.UserID = bson.NewObjectID().Hex()
@@ -213,10 +219,13 @@ func RegisterUser(client *mongo.Client) gin.HandlerFunc {
 		user.UpdatedAt = time.Now()
 		user.Password = hashedPassword
 		user.AuthProvider = "local"
+		// admins are made with PUT /admin/users/{user_id}/role or the role
+		// command, never by signing up
+		user.Role = "USER"
 
 		result, err := usersCollection.InsertOne(ctx, user)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+			c.Error(apperr.Internal("Failed to create user", err))
 			return
 		}
 		c.JSON(http.StatusCreated, result)
@@ -233,14 +242,15 @@ func RegisterUser(client *mongo.Client) gin.HandlerFunc {
 // @Success 200 {object} models.UserResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /login [post]
 func LoginUser(client *mongo.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		defer observeAuth(c, "password")
 		var userLogin models.UserLogin
 
-		if err := c.ShouldBindJSON(&userLogin); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalide input data"})
+		if !bindJSON(c, &userLogin) {
 			return
 		}
 
@@ -252,30 +262,30 @@ func LoginUser(client *mongo.Client) gin.HandlerFunc {
 		var foundUser models.User
 		err := userCollection.FindOne(ctx, bson.D{{Key: "email", Value: userLogin.Email}}).Decode(&foundUser)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+			c.Error(apperr.Unauthorized("invalid_credentials", "Invalid email or password"))
 			return
 		}
 
 		if foundUser.AuthProvider != "local" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Please sign in with " + foundUser.AuthProvider})
+			c.Error(apperr.Unauthorized("wrong_auth_provider", "Please sign in with "+foundUser.AuthProvider))
 			return
 		}
 
 		err = bcrypt.CompareHashAndPassword([]byte(foundUser.Password), []byte(userLogin.Password))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+			c.Error(apperr.Unauthorized("invalid_credentials", "Invalid email or password"))
 			return
 		}
 
 		token, refreshToken, err := utils.GenerateAllTokens(foundUser.Email, foundUser.FirstName, foundUser.LastName, foundUser.Role, foundUser.UserID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+			c.Error(apperr.Internal("Failed to generate tokens", err))
 			return
 		}
 
 		err = utils.UpdateAllTokens(foundUser.UserID, token, refreshToken, client)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tokens"})
+			c.Error(apperr.Internal("Failed to update tokens", err))
 			return
 		}
 		http.SetCookie(c.Writer, &http.Cookie{
@@ -324,23 +334,16 @@ func GetUser(client *mongo.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, exists := c.Get("userID")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+			c.Error(apperr.Unauthenticated(nil))
 			return
 		}
 
 		ctx, cancel := context.WithTimeout(c, 100*time.Second)
 		defer cancel()
 
-		userCollection := database.OpenCollection("users", client)
-
-		var foundUser models.User
-		err := userCollection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&foundUser)
+		foundUser, err := FindUser(ctx, client, userID.(string))
 		if err != nil {
-			if err == mongo.ErrNoDocuments {
-				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			} else {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error", "details": err.Error()})
-			}
+			c.Error(err)
 			return
 		}
 
@@ -372,17 +375,13 @@ func LogoutHandler(client *mongo.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var userLogout models.LogoutRequest
 
-		err := c.ShouldBindJSON(&userLogout)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		if !bindJSON(c, &userLogout) {
 			return
 		}
 
-		fmt.Println("User ID from Logout request:", userLogout.UserID)
-
-		err = utils.UpdateAllTokens(userLogout.UserID, "", "", client)
+		err := utils.UpdateAllTokens(userLogout.UserID, "", "", client)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error logging out"})
+			c.Error(apperr.Internal("Error logging out", err))
 			return
 		}
 		http.SetCookie(c.Writer, &http.Cookie{
@@ -420,20 +419,21 @@ func LogoutHandler(client *mongo.Client) gin.HandlerFunc {
 // @Router /refresh [post]
 func RefreshTokenHandler(client *mongo.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		defer observeAuth(c, "refresh")
 		ctx, cancel := context.WithTimeout(c, 100*time.Second)
 		defer cancel()
 
 		refreshToken, err := c.Cookie("refresh_token")
 		if err != nil {
-			fmt.Println("error", err.Error())
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unable to retrieve refresh token from cookie"})
+			logger.DebugContext(c, "refresh token cookie missing", "error", err)
+			c.Error(apperr.Unauthorized("missing_refresh_token", "Unable to retrieve refresh token from cookie").WithCause(err))
 			return
 		}
 
 		claim, err := utils.ValidateRefreshToken(refreshToken)
 		if err != nil || claim == nil {
-			fmt.Println("error", err.Error())
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+			logger.InfoContext(c, "refresh token rejected", "error", err)
+			c.Error(apperr.Unauthorized("invalid_refresh_token", "Invalid or expired refresh token").WithCause(err))
 			return
 		}
 
@@ -442,14 +442,14 @@ func RefreshTokenHandler(client *mongo.Client) gin.HandlerFunc {
 		var user models.User
 		err = userCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: claim.UserID}}).Decode(&user)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Error(apperr.Unauthorized("user_not_found", "User not found"))
 			return
 		}
 
 		newToken, newRefreshToken, _ := utils.GenerateAllTokens(user.Email, user.FirstName, user.LastName, user.Role, user.UserID)
 		err = utils.UpdateAllTokens(user.UserID, newToken, newRefreshToken, client)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating tokens"})
+			c.Error(apperr.Internal("Error updating tokens", err))
 			return
 		}
 
@@ -470,18 +470,13 @@ func RefreshTokenHandler(client *mongo.Client) gin.HandlerFunc {
 // @Success 200 {object} models.ErrorResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /request-reset [post]
 func RequestResetPassword(client *mongo.Client, mailChan chan models.MailData) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req models.PasswordResetRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
-			return
-		}
-		validate := validator.New()
-		if err := validate.Struct(req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		if !bindJSON(c, &req) {
 			return
 		}
 
@@ -492,13 +487,13 @@ func RequestResetPassword(client *mongo.Client, mailChan chan models.MailData) g
 		var user models.User
 		err := usersCollection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			c.Error(apperr.NotFound("user_not_found", "User not found"))
 			return
 		}
 
 		token, err := utils.GeneratePasswordResetToken(user.UserID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate reset token"})
+			c.Error(apperr.Internal("Failed to generate reset token", err))
 			return
 		}
 
@@ -511,21 +506,22 @@ func RequestResetPassword(client *mongo.Client, mailChan chan models.MailData) g
 			}},
 		)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user with reset token"})
+			c.Error(apperr.Internal("Failed to update user with reset token", err))
 			return
 		}
 
 		resetLink := fmt.Sprintf("http://localhost:5173/reset-password?token=%s", token)
 		mailData := models.MailData{
-			To:       user.Email,
-			From:     "no-reply@movieapp.com",
-			Subject:  "Password Reset",
-			Content:  resetLink,
-			Template: "password-reset.html",
+			To:           user.Email,
+			From:         "no-reply@movieapp.com",
+			Subject:      "Password Reset",
+			Content:      resetLink,
+			Template:     "password-reset.html",
+			TraceContext: tracing.Carrier(c),
 		}
 
 		mailChan <- mailData
-		fmt.Println("Sending password reset email:", mailData)
+		logger.InfoContext(c, "password reset email queued", "user_id", user.UserID)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Password reset email sent"})
 	}
@@ -549,13 +545,7 @@ func ResetPassword(client *mongo.Client) gin.HandlerFunc {
 			Token       string `json:"token" validate:"required"`
 			NewPassword string `json:"new_password" validate:"required,min=6"`
 		}
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
-			return
-		}
-		validate := validator.New()
-		if err := validate.Struct(req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		if !bindJSON(c, &req) {
 			return
 		}
 
@@ -566,18 +556,18 @@ func ResetPassword(client *mongo.Client) gin.HandlerFunc {
 		var user models.User
 		err := usersCollection.FindOne(ctx, bson.M{"password_reset_token": req.Token}).Decode(&user)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid or expired token"})
+			c.Error(apperr.NotFound("reset_token_not_found", "Invalid or expired token"))
 			return
 		}
 
 		if time.Now().After(user.PasswordResetExpires) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Token has expired"})
+			c.Error(apperr.BadRequest("reset_token_expired", "Token has expired"))
 			return
 		}
 
 		hashedPassword, err := HashPassword(req.NewPassword)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			c.Error(apperr.Internal("Failed to hash password", err))
 			return
 		}
 
@@ -591,7 +581,7 @@ func ResetPassword(client *mongo.Client) gin.HandlerFunc {
 			}},
 		)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+			c.Error(apperr.Internal("Failed to reset password", err))
 			return
 		}
 
@@ -615,50 +605,22 @@ func ResetPassword(client *mongo.Client) gin.HandlerFunc {
 func UpdateUser(client *mongo.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, exists := c.Get("userID")
-		log.Println("this is user Id ", userID)
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+			c.Error(apperr.Unauthenticated(nil))
 			return
 		}
 
 		var updateData models.UpdateUser
-		if err := c.ShouldBindJSON(&updateData); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+		if !bindJSON(c, &updateData) {
 			return
 		}
 
 		ctx, cancel := context.WithTimeout(c, 100*time.Second)
 		defer cancel()
 
-		usersCollection := database.OpenCollection("users", client)
-
-		updateFields := bson.M{
-			"updated_at":       time.Now(),
-			"first_name":       updateData.FirstName,
-			"email":            updateData.Email,
-			"last_name":        updateData.LastName,
-			"favourite_genres": updateData.FavouriteGenres,
-		}
-
-		result, err := usersCollection.UpdateOne(
-			ctx,
-			bson.M{"user_id": userID},
-			bson.M{"$set": updateFields},
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user", "details": err.Error()})
-			return
-		}
-
-		if result.MatchedCount == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			return
-		}
-
-		var updatedUser models.User
-		err = usersCollection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&updatedUser)
+		updatedUser, err := UpdateProfile(ctx, client, userID.(string), updateData)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated user"})
+			c.Error(err)
 			return
 		}
 
@@ -674,3 +636,100 @@ func UpdateUser(client *mongo.Client) gin.HandlerFunc {
 		})
 	}
 }
+
+// FindUser returns the user with userID. Errors are *apperr.Error.
+func FindUser(ctx context.Context, client *mongo.Client, userID string) (models.User, error) {
+	var user models.User
+	err := database.OpenCollection("users", client).FindOne(ctx, bson.M{"user_id": userID}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, apperr.NotFound("user_not_found", "User not found")
+	}
+	if err != nil {
+		return user, apperr.Internal("Database error", err)
+	}
+	return user, nil
+}
+
+// UpdateProfile replaces the names, email and favourite genres of the user
+// and returns them as stored. Errors are *apperr.Error.
+func UpdateProfile(ctx context.Context, client *mongo.Client, userID string, updateData models.UpdateUser) (models.User, error) {
+	usersCollection := database.OpenCollection("users", client)
+
+	updateFields := bson.M{
+		"updated_at":       time.Now(),
+		"first_name":       updateData.FirstName,
+		"email":            updateData.Email,
+		"last_name":        updateData.LastName,
+		"favourite_genres": updateData.FavouriteGenres,
+	}
+
+	result, err := usersCollection.UpdateOne(
+		ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$set": updateFields},
+	)
+	if err != nil {
+		return models.User{}, apperr.Internal("Failed to update user", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return models.User{}, apperr.NotFound("user_not_found", "User not found")
+	}
+
+	var updatedUser models.User
+	err = usersCollection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&updatedUser)
+	if err != nil {
+		return models.User{}, apperr.Internal("Failed to fetch updated user", err)
+	}
+	return updatedUser, nil
+}
+
+// UpdateUserRole godoc
+// @Summary Change a user's role
+// @Description Make a user an admin or a regular user. The new role applies from their next token refresh. Admins cannot change their own role. Admin only.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param user_id path string true "User ID"
+// @Param role body models.RoleUpdate true "New role"
+// @Success 200 {object} models.RoleUpdate
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/users/{user_id}/role [put]
+func UpdateUserRole(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		targetID := c.Param("user_id")
+		userID, err := utils.GetUserIDFromContext(c)
+		if err != nil {
+			c.Error(apperr.Unauthenticated(err))
+			return
+		}
+		if targetID == userID {
+			c.Error(apperr.BadRequest("own_role", "Admins cannot change their own role"))
+			return
+		}
+		var req models.RoleUpdate
+		if !bindJSON(c, &req) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		result, err := database.OpenCollection("users", client).UpdateOne(ctx,
+			bson.M{"user_id": targetID},
+			bson.M{"$set": bson.M{"role": req.Role, "update_at": time.Now()}})
+		if err != nil {
+			c.Error(apperr.Internal("Failed to update role", err))
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.Error(apperr.NotFound("user_not_found", "User not found"))
+			return
+		}
+		publish(ctx, events.RoleChanged, targetID, events.RoleChange{Role: req.Role})
+		c.JSON(http.StatusOK, req)
+	}
+}
@@ -0,0 +1,277 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/audit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/webhooks"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// webhookWorker is woken when deliveries are replayed, without one they wait
+// for the next poll of whichever worker runs
+var webhookWorker *webhooks.Worker
+
+// InitWebhooks sets the worker sending webhook deliveries
+func InitWebhooks(worker *webhooks.Worker) {
+	webhookWorker = worker
+}
+
+// ReplayRequest names the failed deliveries to send again, all of them when
+// empty
+type ReplayRequest struct {
+	DeliveryIDs []string `json:"delivery_ids" validate:"max=100"`
+}
+
+// AddWebhook godoc
+// @Summary Add a webhook
+// @Description Subscribe a URL to catalogue events: movie.created, movie.updated, ranking.completed, genre.changed and catalogue.reranked. Each event is POSTed as JSON with X-Webhook-Event, X-Webhook-Delivery, X-Webhook-Timestamp and X-Webhook-Signature headers, the signature being "sha256=" and the hex HMAC-SHA256 of the timestamp, a dot and the body, keyed with the secret. The URL must be http or https on a public host: localhost and loopback, private and link-local addresses are refused here, and when a delivery connects to whatever the name resolves to. The secret is generated unless given and only returned here. Admin only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param webhook body webhooks.Input true "Webhook settings"
+// @Success 201 {object} webhooks.Webhook
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks [post]
+func AddWebhook(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input webhooks.Input
+		if !bindJSON(c, &input) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		webhook, err := webhooks.Create(ctx, client, input)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to add webhook", err))
+			return
+		}
+		c.Set(audit.TargetContextKey, webhook.WebhookID)
+		c.JSON(http.StatusCreated, webhook)
+	}
+}
+
+// GetWebhooks godoc
+// @Summary Get webhooks
+// @Description Get the webhooks, oldest first, without their secrets. Admin only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Page number, from 1"
+// @Param page_size query int false "Webhooks per page, at most 100"
+// @Success 200 {object} models.Page[webhooks.Webhook]
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks [get]
+func GetWebhooks(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, pageSize := getPagination(c)
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		found, total, err := webhooks.List(ctx, client, (page-1)*pageSize, pageSize)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to fetch webhooks", err))
+			return
+		}
+		c.JSON(http.StatusOK, models.Page[webhooks.Webhook]{Items: found, Page: page, PageSize: pageSize, Total: total})
+	}
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook
+// @Description Get a webhook without its secret. Admin only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} webhooks.Webhook
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks/{id} [get]
+func GetWebhook(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		webhook, err := webhooks.Get(ctx, client, c.Param("id"))
+		if err == mongo.ErrNoDocuments {
+			c.Error(apperr.NotFound("webhook_not_found", "Webhook not found"))
+			return
+		}
+		if err != nil {
+			c.Error(apperr.Internal("Failed to fetch webhook", err))
+			return
+		}
+		c.JSON(http.StatusOK, webhook)
+	}
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook
+// @Description Replace the URL, events, description and active flag of a webhook. The secret is only replaced when one is sent. Deliveries to an inactive webhook fail at once and can be replayed once it is active again. Admin only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Param webhook body webhooks.Input true "Webhook settings"
+// @Success 200 {object} webhooks.Webhook
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks/{id} [put]
+func UpdateWebhook(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input webhooks.Input
+		if !bindJSON(c, &input) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		webhook, err := webhooks.Update(ctx, client, c.Param("id"), input)
+		if err == mongo.ErrNoDocuments {
+			c.Error(apperr.NotFound("webhook_not_found", "Webhook not found"))
+			return
+		}
+		if err != nil {
+			c.Error(apperr.Internal("Failed to update webhook", err))
+			return
+		}
+		c.JSON(http.StatusOK, webhook)
+	}
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Delete a webhook along with its deliveries. Admin only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks/{id} [delete]
+func DeleteWebhook(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		deleted, err := webhooks.Delete(ctx, client, c.Param("id"))
+		if err != nil {
+			c.Error(apperr.Internal("Failed to delete webhook", err))
+			return
+		}
+		if !deleted {
+			c.Error(apperr.NotFound("webhook_not_found", "Webhook not found"))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "webhook deleted"})
+	}
+}
+
+// GetWebhookDeliveries godoc
+// @Summary Get the deliveries of a webhook
+// @Description Get the delivery log of a webhook, newest first: each delivery's payload, status, attempts and the latest attempts with their response status or error. Pending deliveries are retried with a doubling wait, failed ones ran out of attempts. Admin only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Param status query string false "pending, succeeded or failed"
+// @Param page query int false "Page number, from 1"
+// @Param page_size query int false "Deliveries per page, at most 100"
+// @Success 200 {object} models.Page[webhooks.Delivery]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks/{id}/deliveries [get]
+func GetWebhookDeliveries(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := c.Query("status")
+		switch status {
+		case "", webhooks.Pending, webhooks.Succeeded, webhooks.Failed:
+		default:
+			c.Error(apperr.InvalidParameter("Invalid status, expected pending, succeeded or failed"))
+			return
+		}
+		page, pageSize := getPagination(c)
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		webhookID := c.Param("id")
+		if _, err := webhooks.Get(ctx, client, webhookID); err == mongo.ErrNoDocuments {
+			c.Error(apperr.NotFound("webhook_not_found", "Webhook not found"))
+			return
+		} else if err != nil {
+			c.Error(apperr.Internal("Failed to fetch deliveries", err))
+			return
+		}
+		deliveries, total, err := webhooks.Deliveries(ctx, client, webhookID, status, (page-1)*pageSize, pageSize)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to fetch deliveries", err))
+			return
+		}
+		c.JSON(http.StatusOK, models.Page[webhooks.Delivery]{Items: deliveries, Page: page, PageSize: pageSize, Total: total})
+	}
+}
+
+// ReplayWebhookDeliveries godoc
+// @Summary Replay failed deliveries of a webhook
+// @Description Queue failed deliveries of a webhook again, with a fresh set of attempts: the ones named in delivery_ids, or all of them when the body or the list is empty. Admin only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Param replay body ReplayRequest false "Deliveries to replay"
+// @Success 200 {object} map[string]int64
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks/{id}/deliveries/replay [post]
+func ReplayWebhookDeliveries(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ReplayRequest
+		if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		webhookID := c.Param("id")
+		if _, err := webhooks.Get(ctx, client, webhookID); err == mongo.ErrNoDocuments {
+			c.Error(apperr.NotFound("webhook_not_found", "Webhook not found"))
+			return
+		} else if err != nil {
+			c.Error(apperr.Internal("Failed to replay deliveries", err))
+			return
+		}
+		replayed, err := webhooks.Replay(ctx, client, webhookID, req.DeliveryIDs)
+		if err != nil {
+			c.Error(apperr.Internal("Failed to replay deliveries", err))
+			return
+		}
+		if replayed > 0 && webhookWorker != nil {
+			webhookWorker.Wake()
+		}
+		c.JSON(http.StatusOK, gin.H{"replayed": replayed})
+	}
+}
@@ -2,28 +2,30 @@
 package database
 
 import (
-	"fmt"
-	"log"
 	"os"
 
 	"github.com/joho/godotenv"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/logging"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+var logger = logging.For("database")
+
 func Connect() *mongo.Client {
 	err := godotenv.Load(".env")
 	if err != nil {
-		log.Fatal("Warning: unable to find .env file")
+		logger.Error("unable to find .env file")
+		os.Exit(1)
 	}
 
 	MongoDB := os.Getenv("MONGODB_URI")
 	if MongoDB == "" {
-		log.Fatal("MONGODB_URI not set!")
+		logger.Error("MONGODB_URI not set")
+		os.Exit(1)
 	}
-	fmt.Println("MongoDB URI: ", MongoDB)
 
-	clientOptions := options.Client().ApplyURI(MongoDB)
+	clientOptions := options.Client().ApplyURI(MongoDB).SetMonitor(commandMonitor())
 	client, err := mongo.Connect(clientOptions)
 	if err != nil {
 		return nil
@@ -34,10 +36,10 @@ func Connect() *mongo.Client {
 func OpenCollection(collectionName string, client *mongo.Client) *mongo.Collection {
 	err := godotenv.Load(".env")
 	if err != nil {
-		log.Fatal("Warning : unable to find .env file")
+		logger.Error("unable to find .env file")
+		os.Exit(1)
 	}
 	databaseName := os.Getenv("DATABASE_NAME")
-	fmt.Println("DATABASE_NAME: ", databaseName)
 
 	collection := client.Database(databaseName).Collection(collectionName)
 	if collection == nil {
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/metrics"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/tracing"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// commandMonitor times every command the driver sends, by command name only
+// so collections and filters do not turn into labels, and traces it as a
+// child of the span of the operation. Commands are not put on spans as they
+// can hold user data.
+func commandMonitor() *event.CommandMonitor {
+	// spans of commands in flight by driver request id, which is unique
+	// across connections
+	var spans sync.Map
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			name := e.CommandName
+			attrs := []attribute.KeyValue{
+				attribute.String("db.system.name", "mongodb"),
+				attribute.String("db.namespace", e.DatabaseName),
+				attribute.String("db.operation.name", e.CommandName),
+			}
+			if collection, ok := e.Command.Lookup(e.CommandName).StringValueOK(); ok {
+				name += " " + collection
+				attrs = append(attrs, attribute.String("db.collection.name", collection))
+			}
+			_, span := tracing.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			spans.Store(e.RequestID, span)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			metrics.MongoCommand(e.CommandName, e.Duration, false)
+			if span, ok := spans.LoadAndDelete(e.RequestID); ok {
+				tracing.End(span.(trace.Span), nil)
+			}
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			metrics.MongoCommand(e.CommandName, e.Duration, true)
+			if span, ok := spans.LoadAndDelete(e.RequestID); ok {
+				tracing.End(span.(trace.Span), e.Failure)
+			}
+		},
+	}
+}
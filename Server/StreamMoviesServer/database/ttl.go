@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// EnsureTTLIndex makes documents of a collection expire ttl after the time in
+// field through the TTL index called name. A ttl of 0 drops the index so
+// documents are kept, an existing index with another ttl is changed in place.
+func EnsureTTLIndex(ctx context.Context, client *mongo.Client, collectionName, name, field string, ttl time.Duration) error {
+	expireAfter := int32(ttl / time.Second)
+
+	collection := OpenCollection(collectionName, client)
+	specs, err := collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return err
+	}
+	var existing *mongo.IndexSpecification
+	for _, spec := range specs {
+		if spec.Name == name {
+			existing = &spec
+		}
+	}
+
+	switch {
+	case expireAfter == 0 && existing == nil:
+		return nil
+	case expireAfter == 0:
+		return collection.Indexes().DropOne(ctx, name)
+	case existing == nil:
+		_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: field, Value: 1}},
+			Options: options.Index().SetName(name).SetExpireAfterSeconds(expireAfter),
+		})
+		return err
+	case existing.ExpireAfterSeconds == nil || *existing.ExpireAfterSeconds != expireAfter:
+		command := bson.D{
+			{Key: "collMod", Value: collectionName},
+			{Key: "index", Value: bson.M{"name": name, "expireAfterSeconds": expireAfter}},
+		}
+		return collection.Database().RunCommand(ctx, command).Err()
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package database
+
+import "go.mongodb.org/mongo-driver/v2/bson"
+
+// Versioned adds to a movie update what marks the movie as changed: version
+// goes up by one and updated_at becomes the server time. Every update that
+// changes what clients see of a movie goes through it, as ETags are derived
+// from the version.
+func Versioned(update bson.M) bson.M {
+	update["$inc"] = bson.M{"version": 1}
+	update["$currentDate"] = bson.M{"updated_at": true}
+	return update
+}
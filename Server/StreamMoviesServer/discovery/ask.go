@@ -0,0 +1,206 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/embeddings"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/llm"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/metrics"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/tracing"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrModelFailed = errors.New("language model request failed")
+
+// maxAttempts is how often the model may answer before an invalid reply is
+// returned as an error
+const maxAttempts = 2
+
+// moodCandidates is how many more movies than asked for are fetched when a
+// mood is given, so ordering by it has something to choose from
+const moodCandidates = 5
+
+const systemPrompt = `You translate requests for movies into a search over a movie catalogue.
+Reply with one JSON object that follows this JSON schema and nothing else:
+%s
+Only fill in fields the request asks for. Use genre and ranking names exactly as listed.
+A decade such as "the 90s" means year_from 1990 and year_to 1999.
+Words that describe the kind of movie but match no field go into mood.`
+
+// Answer is the result of a request: the query the model read from it, the
+// catalogue filters it became and the matching movies
+type Answer struct {
+	Query   Query          `json:"query"`
+	Filters string         `json:"filters"`
+	Movies  []models.Movie `json:"movies"`
+}
+
+type Asker struct {
+	Client *mongo.Client
+	Model  llm.Model
+	// Embeddings orders results by their mood when set
+	Embeddings *embeddings.Store
+}
+
+// Interpret asks the model for a query and validates it, giving the model one
+// more try with the validation error when its first reply is rejected
+func (a *Asker) Interpret(ctx context.Context, text string) (Query, error) {
+	vocabulary, err := LoadVocabulary(ctx, a.Client)
+	if err != nil {
+		return Query{}, err
+	}
+	return a.interpret(ctx, vocabulary, text)
+}
+
+func (a *Asker) interpret(ctx context.Context, vocabulary Vocabulary, text string) (Query, error) {
+	system := fmt.Sprintf(systemPrompt, vocabulary.Schema())
+
+	prompt := text
+	for attempt := 1; ; attempt++ {
+		started := time.Now()
+		llmCtx, span := tracing.Start(ctx, "llm.generate", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("gen_ai.operation.name", "ask"), attribute.Int("attempt", attempt)))
+		reply, err := a.Model.Generate(llmCtx, system, prompt, true)
+		tracing.End(span, err)
+		if err != nil {
+			metrics.LLMRequest("ask", metrics.Error, time.Since(started))
+			return Query{}, fmt.Errorf("%w: %v", ErrModelFailed, err)
+		}
+		metrics.LLMRequest("ask", metrics.Success, time.Since(started))
+		query, err := vocabulary.Parse(reply)
+		var invalid *ValidationError
+		if err == nil || !errors.As(err, &invalid) || attempt == maxAttempts {
+			return query, err
+		}
+		prompt = retryPrompt(text, invalid.Reason)
+	}
+}
+
+// retryPrompt asks again for text after a reply was rejected for reason
+func retryPrompt(text, reason string) string {
+	return fmt.Sprintf("%s\n\nYour previous reply was rejected (%s). Reply again with only the corrected JSON object.", text, reason)
+}
+
+// Ask interprets text and returns up to limit matching movies, best ranked
+// first or closest to the mood when one was given
+func (a *Asker) Ask(ctx context.Context, text string, limit int) (*Answer, error) {
+	query, err := a.Interpret(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	values, err := a.filterValues(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	answer := &Answer{Query: query, Filters: values.Encode(), Movies: []models.Movie{}}
+	if values == nil {
+		// the person asked for is not in the catalogue
+		return answer, nil
+	}
+	filter, err := catalogue.ParseMovieFilter(values)
+	if err != nil {
+		return nil, &ValidationError{Reason: err.Error()}
+	}
+
+	fetch := limit
+	if query.Mood != "" && a.Embeddings != nil {
+		fetch = limit * moodCandidates
+	}
+	opts := options.Find().
+		SetProjection(bson.M{"embedding": 0, "embedding_model": 0, "embedding_hash": 0}).
+		SetSort(bson.D{{Key: "ranking.ranking_value", Value: 1}, {Key: "imdb_id", Value: 1}}).
+		SetLimit(int64(fetch))
+	cursor, err := database.OpenCollection("movies", a.Client).Find(ctx, filter.BSON(), opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &answer.Movies); err != nil {
+		return nil, err
+	}
+
+	if fetch > limit {
+		if err := a.orderByMood(ctx, query.Mood, answer.Movies); err != nil {
+			return nil, err
+		}
+	}
+	if len(answer.Movies) > limit {
+		answer.Movies = answer.Movies[:limit]
+	}
+	return answer, nil
+}
+
+// filterValues builds the query string catalogue.ParseMovieFilter reads, so
+// an answer filters exactly like GET /movies. It returns nil values when the
+// query names a person we do not know.
+func (a *Asker) filterValues(ctx context.Context, query Query) (url.Values, error) {
+	values := url.Values{}
+	setList := func(key string, list []string) {
+		if len(list) > 0 {
+			values.Set(key, strings.Join(list, ","))
+		}
+	}
+	setInt := func(key string, value int) {
+		if value > 0 {
+			values.Set(key, strconv.Itoa(value))
+		}
+	}
+	setList("genre", query.Genres)
+	setList("ranking", query.Rankings)
+	setList("language", query.Languages)
+	setList("country", query.Countries)
+	setInt("max_ranking", query.MaxRanking)
+	setInt("year_from", query.YearFrom)
+	setInt("year_to", query.YearTo)
+	if query.Title != "" {
+		values.Set("q", query.Title)
+	}
+
+	if query.Person != "" {
+		var person models.Person
+		filter := bson.M{"name": bson.M{"$regex": "^" + regexp.QuoteMeta(strings.TrimSpace(query.Person)) + "$", "$options": "i"}}
+		err := database.OpenCollection("people", a.Client).FindOne(ctx, filter).Decode(&person)
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		values.Set("person", person.PersonID)
+		if query.PersonRole != "" {
+			values.Set("person_role", query.PersonRole)
+		}
+	}
+	return values, nil
+}
+
+// orderByMood sorts movies by how close they are to the mood, keeping the
+// ranking order among movies without a vector from the current embedder
+func (a *Asker) orderByMood(ctx context.Context, mood string, movies []models.Movie) error {
+	imdbIDs := make([]string, len(movies))
+	for i, movie := range movies {
+		imdbIDs[i] = movie.ImdbID
+	}
+	scores, err := a.Embeddings.Score(ctx, mood, imdbIDs)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(movies, func(i, j int) bool {
+		return scores[movies[i].ImdbID] > scores[movies[j].ImdbID]
+	})
+	return nil
+}
@@ -0,0 +1,119 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/llm"
+)
+
+const request = "a funny sci-fi from the 90s"
+
+func TestInterpretRetriesOnce(t *testing.T) {
+	rejected := retryPrompt(request, `unknown genre "Western"`)
+	tests := []struct {
+		name      string
+		responses map[string]string
+		want      Query
+		wantErr   func(error) bool
+	}{
+		{
+			name:      "valid first reply",
+			responses: map[string]string{request: `{"genres":["comedy"],"year_from":1990,"year_to":1999}`},
+			want:      Query{Genres: []string{"Comedy"}, YearFrom: 1990, YearTo: 1999},
+		},
+		{
+			name: "corrected after the rejection",
+			responses: map[string]string{
+				request:  `{"genres":["Western"]}`,
+				rejected: `{"genres":["Sci-Fi"]}`,
+			},
+			want: Query{Genres: []string{"Sci-Fi"}},
+		},
+		{
+			// a third request would find no reply and fail as ErrModelFailed
+			name: "rejected twice",
+			responses: map[string]string{
+				request:  `{"genres":["Western"]}`,
+				rejected: `{"genres":["Western"]}`,
+			},
+			wantErr: func(err error) bool {
+				var invalid *ValidationError
+				return errors.As(err, &invalid)
+			},
+		},
+		{
+			name:      "model failure is not retried",
+			responses: map[string]string{},
+			wantErr: func(err error) bool {
+				return errors.Is(err, ErrModelFailed)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asker := &Asker{Model: &llm.Fake{Responses: tt.responses}}
+			got, err := asker.interpret(context.Background(), testVocabulary, request)
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Fatalf("interpret error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("interpret: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("interpret = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterValuesRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+		want  catalogue.MovieFilter
+	}{
+		{
+			name:  "empty",
+			query: Query{},
+			want:  catalogue.MovieFilter{},
+		},
+		{
+			name:  "lists",
+			query: Query{Genres: []string{"Comedy", "Sci-Fi"}, Rankings: []string{"Excellent"}, Languages: []string{"en", "fr"}, Countries: []string{"GB"}},
+			want:  catalogue.MovieFilter{GenreNames: []string{"Comedy", "Sci-Fi"}, Rankings: []string{"Excellent"}, Languages: []string{"en", "fr"}, Countries: []string{"GB"}},
+		},
+		{
+			name:  "numbers and title",
+			query: Query{MaxRanking: 2, YearFrom: 1990, YearTo: 1999, Title: "matrix"},
+			want:  catalogue.MovieFilter{MaxRanking: 2, YearFrom: 1990, YearTo: 1999, TitleSearch: "matrix"},
+		},
+		{
+			name:  "mood only orders results",
+			query: Query{Mood: "feel-good"},
+			want:  catalogue.MovieFilter{},
+		},
+	}
+	asker := &Asker{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := asker.filterValues(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("filterValues: %v", err)
+			}
+			got, err := catalogue.ParseMovieFilter(values)
+			if err != nil {
+				t.Fatalf("ParseMovieFilter(%s): %v", values.Encode(), err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMovieFilter(%s) = %+v, want %+v", values.Encode(), got, tt.want)
+			}
+		})
+	}
+}
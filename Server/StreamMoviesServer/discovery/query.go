@@ -0,0 +1,163 @@
+// Package discovery turns a free text request such as "a feel-good sci-fi
+// from the 90s" into catalogue filters with a language model and runs them
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/validation"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Query is the structured form of a request. Every field is optional, the
+// model leaves out whatever the text does not ask for. Mood keeps the
+// descriptive words no filter covers, they are used to order the results.
+type Query struct {
+	Genres     []string `json:"genres,omitempty"`
+	Rankings   []string `json:"rankings,omitempty"`
+	MaxRanking int      `json:"max_ranking,omitempty" validate:"omitempty,min=1"`
+	YearFrom   int      `json:"year_from,omitempty" validate:"omitempty,min=1870,max=2200"`
+	YearTo     int      `json:"year_to,omitempty" validate:"omitempty,min=1870,max=2200,gtefield=YearFrom"`
+	Person     string   `json:"person,omitempty" validate:"omitempty,max=200"`
+	PersonRole string   `json:"person_role,omitempty" validate:"omitempty,max=100"`
+	Languages  []string `json:"languages,omitempty" validate:"omitempty,dive,len=2,lowercase"`
+	Countries  []string `json:"countries,omitempty" validate:"omitempty,dive,iso3166_1_alpha2"`
+	Title      string   `json:"title,omitempty" validate:"omitempty,max=200"`
+	Mood       string   `json:"mood,omitempty" validate:"omitempty,max=300"`
+}
+
+// ValidationError is a model reply that does not match the schema
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid query: " + e.Reason
+}
+
+// Vocabulary lists the genre and ranking names a query may use
+type Vocabulary struct {
+	Genres   []string
+	Rankings []string
+}
+
+func LoadVocabulary(ctx context.Context, client *mongo.Client) (Vocabulary, error) {
+	var vocabulary Vocabulary
+	var genres []models.Genre
+	cursor, err := database.OpenCollection("genres", client).Find(ctx, bson.M{})
+	if err != nil {
+		return vocabulary, err
+	}
+	if err := cursor.All(ctx, &genres); err != nil {
+		return vocabulary, err
+	}
+	for _, genre := range genres {
+		vocabulary.Genres = append(vocabulary.Genres, genre.GenreName)
+	}
+
+	var rankings []models.Ranking
+	cursor, err = database.OpenCollection("rankings", client).Find(ctx, bson.M{})
+	if err != nil {
+		return vocabulary, err
+	}
+	if err := cursor.All(ctx, &rankings); err != nil {
+		return vocabulary, err
+	}
+	for _, ranking := range rankings {
+		vocabulary.Rankings = append(vocabulary.Rankings, ranking.RankingName)
+	}
+	return vocabulary, nil
+}
+
+// Schema is the JSON schema replies must follow, with genres and rankings
+// limited to the vocabulary
+func (v Vocabulary) Schema() string {
+	stringArray := func(values []string) map[string]any {
+		items := map[string]any{"type": "string"}
+		if values != nil {
+			items["enum"] = values
+		}
+		return map[string]any{"type": "array", "items": items}
+	}
+	schema := map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"genres":      stringArray(v.Genres),
+			"rankings":    stringArray(v.Rankings),
+			"max_ranking": map[string]any{"type": "integer", "minimum": 1, "description": "worst ranking value to include, 1 is the best ranking"},
+			"year_from":   map[string]any{"type": "integer", "minimum": 1870},
+			"year_to":     map[string]any{"type": "integer", "minimum": 1870},
+			"person":      map[string]any{"type": "string", "description": "full name of an actor or crew member"},
+			"person_role": map[string]any{"type": "string", "description": "cast, crew or a crew job such as Director, only with person"},
+			"languages":   map[string]any{"type": "array", "items": map[string]any{"type": "string", "pattern": "^[a-z]{2}$"}, "description": "ISO 639-1 codes"},
+			"countries":   map[string]any{"type": "array", "items": map[string]any{"type": "string", "pattern": "^[A-Z]{2}$"}, "description": "ISO 3166-1 alpha-2 codes"},
+			"title":       map[string]any{"type": "string", "description": "words of the title when the user names a movie"},
+			"mood":        map[string]any{"type": "string", "description": "remaining descriptive words such as feel-good or strong female lead"},
+		},
+	}
+	data, _ := json.Marshal(schema)
+	return string(data)
+}
+
+// Parse decodes a model reply strictly: one JSON object, no unknown fields,
+// values within range and genres and rankings from the vocabulary, rewritten
+// to their stored spelling
+func (v Vocabulary) Parse(reply string) (Query, error) {
+	var query Query
+	decoder := json.NewDecoder(strings.NewReader(reply))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&query); err != nil {
+		return query, &ValidationError{Reason: err.Error()}
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return query, &ValidationError{Reason: "unexpected text after the JSON object"}
+	}
+
+	if err := validation.Struct(query); err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			return query, &ValidationError{Reason: validationErrors.Error()}
+		}
+		return query, err
+	}
+	if query.PersonRole != "" && query.Person == "" {
+		return query, &ValidationError{Reason: "person_role needs person"}
+	}
+	var err error
+	if query.Genres, err = canonical("genre", query.Genres, v.Genres); err != nil {
+		return query, err
+	}
+	if query.Rankings, err = canonical("ranking", query.Rankings, v.Rankings); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+func canonical(kind string, values, allowed []string) ([]string, error) {
+	byLower := make(map[string]string, len(allowed))
+	for _, name := range allowed {
+		byLower[strings.ToLower(name)] = name
+	}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		name, ok := byLower[strings.ToLower(strings.TrimSpace(value))]
+		if !ok {
+			return nil, &ValidationError{Reason: fmt.Sprintf("unknown %s %q", kind, value)}
+		}
+		result = append(result, name)
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
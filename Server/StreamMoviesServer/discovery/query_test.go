@@ -0,0 +1,76 @@
+package discovery
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var testVocabulary = Vocabulary{
+	Genres:   []string{"Comedy", "Sci-Fi", "Drama"},
+	Rankings: []string{"Excellent", "Good", "Okay"},
+}
+
+func TestParseRejectsInvalidReplies(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+	}{
+		{"unknown field", `{"genres":["Comedy"],"director":"Nolan"}`},
+		{"not an object", `["Comedy"]`},
+		{"text after the object", `{"genres":["Comedy"]} I hope this helps!`},
+		{"two objects", `{"genres":["Comedy"]}{"mood":"dark"}`},
+		{"wrong type", `{"year_from":"1990"}`},
+		{"year out of range", `{"year_from":1200}`},
+		{"years reversed", `{"year_from":1999,"year_to":1990}`},
+		{"bad language", `{"languages":["english"]}`},
+		{"bad country", `{"countries":["XX"]}`},
+		{"role without person", `{"person_role":"Director"}`},
+		{"unknown genre", `{"genres":["Western"]}`},
+		{"unknown ranking", `{"rankings":["Superb"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testVocabulary.Parse(tt.reply)
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("Parse(%s) error = %v, want a ValidationError", tt.reply, err)
+			}
+		})
+	}
+}
+
+func TestParseCanonicalizesVocabulary(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  Query
+	}{
+		{
+			name:  "genres and rankings take their stored spelling",
+			reply: `{"genres":["comedy"," SCI-FI "],"rankings":["excellent"]}`,
+			want:  Query{Genres: []string{"Comedy", "Sci-Fi"}, Rankings: []string{"Excellent"}},
+		},
+		{
+			name:  "empty lists are left out",
+			reply: `{"genres":[],"rankings":[],"mood":"feel-good"}`,
+			want:  Query{Mood: "feel-good"},
+		},
+		{
+			name:  "other fields are kept as sent",
+			reply: `{"year_from":1990,"year_to":1999,"languages":["en"],"countries":["GB"],"person":"Sofia Coppola","person_role":"Director"}`,
+			want:  Query{YearFrom: 1990, YearTo: 1999, Languages: []string{"en"}, Countries: []string{"GB"}, Person: "Sofia Coppola", PersonRole: "Director"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testVocabulary.Parse(tt.reply)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
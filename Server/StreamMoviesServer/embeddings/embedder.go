@@ -0,0 +1,94 @@
+// Package embeddings turns movies into vectors and finds the movies closest
+// to one another. Vectors live on the movie document next to the name of the
+// embedder that produced them and a hash of the text they were made from, so
+// switching embedders or editing a movie marks them stale.
+package embeddings
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/logging"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+)
+
+var logger = logging.For("embeddings")
+
+// Embedder turns texts into vectors of Dimensions floats. Name identifies the
+// model, vectors from different names are never compared.
+type Embedder interface {
+	Name() string
+	Dimensions() int
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// NewEmbedderFromEnv picks the embedder named by EMBEDDER: "hashing" (the
+// default, offline) or "openai", which needs OPENAI_API_KEY and reads
+// EMBEDDING_MODEL
+func NewEmbedderFromEnv() (Embedder, error) {
+	switch strings.ToLower(os.Getenv("EMBEDDER")) {
+	case "", "hashing":
+		return NewHashingEmbedder(defaultHashingDimensions), nil
+	case "openai":
+		return NewOpenAIEmbedder(os.Getenv("OPENAI_API_KEY"), os.Getenv("EMBEDDING_MODEL"))
+	default:
+		return nil, fmt.Errorf("unknown EMBEDDER %q, use hashing or openai", os.Getenv("EMBEDDER"))
+	}
+}
+
+// MovieText is what gets embedded for a movie: title, genres, overview and
+// the admin review
+func MovieText(movie models.Movie) string {
+	genres := make([]string, 0, len(movie.Genre))
+	for _, genre := range movie.Genre {
+		genres = append(genres, genre.GenreName)
+	}
+	parts := []string{movie.Title}
+	if len(genres) > 0 {
+		parts = append(parts, "Genres: "+strings.Join(genres, ", "))
+	}
+	if movie.Overview != "" {
+		parts = append(parts, movie.Overview)
+	}
+	if movie.AdminReview != "" {
+		parts = append(parts, "Review: "+movie.AdminReview)
+	}
+	return strings.Join(parts, "\n")
+}
+
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalize scales vector to unit length in place so cosine similarity is a
+// plain dot product
+func normalize(vector []float32) {
+	var sum float64
+	for _, value := range vector {
+		sum += float64(value) * float64(value)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+}
+
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,84 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const defaultHashingDimensions = 512
+
+// stopWords are too common to say anything about a movie
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "the": true, "of": true, "to": true, "in": true, "is": true,
+	"it": true, "its": true, "his": true, "her": true, "their": true, "for": true, "on": true, "with": true,
+	"as": true, "by": true, "at": true, "from": true, "this": true, "that": true, "be": true, "who": true,
+}
+
+// HashingEmbedder needs no network: words and word pairs are hashed into a
+// fixed number of buckets with a sign, weighted by 1+log(count). Similar
+// wording gives similar vectors, which is enough for "more like this".
+type HashingEmbedder struct {
+	dimensions int
+}
+
+func NewHashingEmbedder(dimensions int) *HashingEmbedder {
+	return &HashingEmbedder{dimensions: dimensions}
+}
+
+func (h *HashingEmbedder) Name() string {
+	return fmt.Sprintf("hashing-%d", h.dimensions)
+}
+
+func (h *HashingEmbedder) Dimensions() int {
+	return h.dimensions
+}
+
+func (h *HashingEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = h.embed(text)
+	}
+	return vectors, nil
+}
+
+func (h *HashingEmbedder) embed(text string) []float32 {
+	words := tokenize(text)
+	counts := map[string]int{}
+	for i, word := range words {
+		counts[word]++
+		if i > 0 {
+			counts[words[i-1]+" "+word]++
+		}
+	}
+
+	vector := make([]float32, h.dimensions)
+	for feature, count := range counts {
+		hash := fnv.New64a()
+		hash.Write([]byte(feature))
+		sum := hash.Sum64()
+		weight := float32(1 + math.Log(float64(count)))
+		if sum&(1<<63) != 0 {
+			weight = -weight
+		}
+		vector[sum%uint64(h.dimensions)] += weight
+	}
+	normalize(vector)
+	return vector
+}
+
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := fields[:0]
+	for _, field := range fields {
+		if len(field) > 1 && !stopWords[field] {
+			words = append(words, field)
+		}
+	}
+	return words
+}
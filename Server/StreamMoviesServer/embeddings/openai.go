@@ -0,0 +1,67 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+const (
+	defaultOpenAIModel = "text-embedding-3-small"
+	openAIBatchSize    = 100
+)
+
+// openAIDimensions lists the vector sizes of the models we know about
+var openAIDimensions = map[string]int{
+	"text-embedding-3-small": 1536,
+	"text-embedding-3-large": 3072,
+	"text-embedding-ada-002": 1536,
+}
+
+type OpenAIEmbedder struct {
+	llm   *openai.LLM
+	model string
+}
+
+func NewOpenAIEmbedder(apiKey, model string) (*OpenAIEmbedder, error) {
+	if apiKey == "" {
+		return nil, errors.New("could not get open ai api key")
+	}
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	if _, ok := openAIDimensions[model]; !ok {
+		return nil, fmt.Errorf("unknown embedding model %q", model)
+	}
+	llm, err := openai.New(openai.WithToken(apiKey), openai.WithEmbeddingModel(model))
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAIEmbedder{llm: llm, model: model}, nil
+}
+
+func (o *OpenAIEmbedder) Name() string {
+	return "openai-" + o.model
+}
+
+func (o *OpenAIEmbedder) Dimensions() int {
+	return openAIDimensions[o.model]
+}
+
+func (o *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += openAIBatchSize {
+		end := min(start+openAIBatchSize, len(texts))
+		batch, err := o.llm.CreateEmbedding(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	for _, vector := range vectors {
+		normalize(vector)
+	}
+	return vectors, nil
+}
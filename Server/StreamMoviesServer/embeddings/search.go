@@ -0,0 +1,176 @@
+package embeddings
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// memoryIndexTTL bounds how long vectors written by another process can go
+// unnoticed by the in-process search
+const memoryIndexTTL = 5 * time.Minute
+
+// Match is a movie and its cosine similarity to the query, from -1 to 1
+type Match struct {
+	ImdbID     string  `json:"imdb_id"`
+	Similarity float64 `json:"similarity"`
+}
+
+type memoryIndex struct {
+	mu       sync.Mutex
+	vectors  []embeddedMovie
+	loadedAt time.Time
+}
+
+// memoryIndexes holds one index per embedder name, shared by every Store
+var memoryIndexes sync.Map
+
+func invalidate(model string) {
+	if value, ok := memoryIndexes.Load(model); ok {
+		index := value.(*memoryIndex)
+		index.mu.Lock()
+		index.loadedAt = time.Time{}
+		index.mu.Unlock()
+	}
+}
+
+// Similar returns up to limit movies closest to vector, best first, leaving
+// out exclude
+func (s *Store) Similar(ctx context.Context, vector []float32, exclude string, limit int) ([]Match, error) {
+	if s.VectorIndex != "" {
+		matches, err := s.vectorSearch(ctx, vector, exclude, limit)
+		if err == nil {
+			return matches, nil
+		}
+		logger.WarnContext(ctx, "vector search failed, searching in process", "index", s.VectorIndex, "error", err)
+	}
+	return s.memorySearch(ctx, vector, exclude, limit)
+}
+
+func (s *Store) vectorSearch(ctx context.Context, vector []float32, exclude string, limit int) ([]Match, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$vectorSearch", Value: bson.M{
+			"index":         s.VectorIndex,
+			"path":          "embedding",
+			"queryVector":   vector,
+			"numCandidates": (limit + 1) * 20,
+			"limit":         limit + 1,
+			"filter":        bson.M{"embedding_model": s.Embedder.Name()},
+		}}},
+		{{Key: "$match", Value: bson.M{"imdb_id": bson.M{"$ne": exclude}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "imdb_id": 1, "score": bson.M{"$meta": "vectorSearchScore"}}}},
+	}
+	cursor, err := database.OpenCollection("movies", s.Client).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var results []struct {
+		ImdbID string  `bson:"imdb_id"`
+		Score  float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	matches := make([]Match, len(results))
+	for i, result := range results {
+		// atlas reports cosine similarity scaled to 0..1
+		matches[i] = Match{ImdbID: result.ImdbID, Similarity: 2*result.Score - 1}
+	}
+	return matches, nil
+}
+
+func (s *Store) memorySearch(ctx context.Context, vector []float32, exclude string, limit int) ([]Match, error) {
+	value, _ := memoryIndexes.LoadOrStore(s.Embedder.Name(), &memoryIndex{})
+	index := value.(*memoryIndex)
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	if time.Since(index.loadedAt) > memoryIndexTTL {
+		opts := options.Find().SetProjection(bson.M{"imdb_id": 1, "embedding": 1, "embedding_model": 1})
+		cursor, err := database.OpenCollection("movies", s.Client).Find(ctx, bson.M{"embedding_model": s.Embedder.Name()}, opts)
+		if err != nil {
+			return nil, err
+		}
+		var vectors []embeddedMovie
+		if err := cursor.All(ctx, &vectors); err != nil {
+			return nil, err
+		}
+		index.vectors = vectors
+		index.loadedAt = time.Now()
+	}
+
+	matches := make([]Match, 0, len(index.vectors))
+	for _, movie := range index.vectors {
+		if movie.ImdbID != exclude {
+			matches = append(matches, Match{ImdbID: movie.ImdbID, Similarity: dot(vector, movie.Embedding)})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Similarity != matches[j].Similarity {
+			return matches[i].Similarity > matches[j].Similarity
+		}
+		return matches[i].ImdbID < matches[j].ImdbID
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// EnsureVectorIndex creates the Atlas vector search index Similar uses when
+// it does not exist yet. Only Atlas and local Atlas deployments support it.
+func (s *Store) EnsureVectorIndex(ctx context.Context) error {
+	searchIndexes := database.OpenCollection("movies", s.Client).SearchIndexes()
+	cursor, err := searchIndexes.List(ctx, options.SearchIndexes().SetName(s.VectorIndex))
+	if err != nil {
+		return err
+	}
+	var existing []bson.M
+	if err := cursor.All(ctx, &existing); err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+	definition := bson.M{"fields": bson.A{
+		bson.M{"type": "vector", "path": "embedding", "numDimensions": s.Embedder.Dimensions(), "similarity": "cosine"},
+		bson.M{"type": "filter", "path": "embedding_model"},
+	}}
+	model := mongo.SearchIndexModel{
+		Definition: definition,
+		Options:    options.SearchIndexes().SetName(s.VectorIndex).SetType("vectorSearch"),
+	}
+	_, err = searchIndexes.CreateOne(ctx, model)
+	return err
+}
+
+// Score embeds text and returns its similarity to each of the movies that
+// has a vector from the current embedder
+func (s *Store) Score(ctx context.Context, text string, imdbIDs []string) (map[string]float64, error) {
+	vectors, err := s.Embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"imdb_id": bson.M{"$in": imdbIDs}, "embedding_model": s.Embedder.Name()}
+	opts := options.Find().SetProjection(bson.M{"imdb_id": 1, "embedding": 1})
+	cursor, err := database.OpenCollection("movies", s.Client).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var movies []embeddedMovie
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	scores := make(map[string]float64, len(movies))
+	for _, movie := range movies {
+		scores[movie.ImdbID] = dot(vectors[0], movie.Embedding)
+	}
+	return scores, nil
+}
@@ -0,0 +1,154 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const embedBatchSize = 64
+
+var ErrMovieNotFound = errors.New("movie not found")
+
+// embeddedMovie is a movie document together with the fields this package
+// keeps on it
+type embeddedMovie struct {
+	models.Movie `bson:",inline"`
+	Embedding    []float32 `bson:"embedding,omitempty"`
+	Model        string    `bson:"embedding_model,omitempty"`
+	Hash         string    `bson:"embedding_hash,omitempty"`
+}
+
+func (m *embeddedMovie) stale(embedder Embedder, hash string) bool {
+	return len(m.Embedding) != embedder.Dimensions() || m.Model != embedder.Name() || m.Hash != hash
+}
+
+// Store embeds movies and searches them. VectorIndex names an Atlas vector
+// search index on the movies collection, when empty or failing the search
+// runs over vectors held in process.
+type Store struct {
+	Client      *mongo.Client
+	Embedder    Embedder
+	VectorIndex string
+}
+
+// NewStoreFromEnv uses the embedder from NewEmbedderFromEnv and the vector
+// search index named by MONGODB_VECTOR_INDEX
+func NewStoreFromEnv(client *mongo.Client) (*Store, error) {
+	embedder, err := NewEmbedderFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Client: client, Embedder: embedder, VectorIndex: os.Getenv("MONGODB_VECTOR_INDEX")}, nil
+}
+
+// EmbedMovie returns the vector of a movie, computing and storing it first
+// when it is missing or stale
+func (s *Store) EmbedMovie(ctx context.Context, imdbID string) ([]float32, error) {
+	var movie embeddedMovie
+	err := database.OpenCollection("movies", s.Client).FindOne(ctx, bson.M{"imdb_id": imdbID}).Decode(&movie)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrMovieNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	text := MovieText(movie.Movie)
+	if !movie.stale(s.Embedder, textHash(text)) {
+		return movie.Embedding, nil
+	}
+	vectors, err := s.embed(ctx, []embeddedMovie{movie})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedAll embeds every movie whose vector is missing or stale, or every
+// movie when force is set, and returns how many were written
+func (s *Store) EmbedAll(ctx context.Context, force bool, progress func(done int)) (int, error) {
+	cursor, err := database.OpenCollection("movies", s.Client).Find(ctx, bson.M{}, options.Find().SetBatchSize(embedBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	done := 0
+	batch := make([]embeddedMovie, 0, embedBatchSize)
+	flush := func() error {
+		_, err := s.embed(ctx, batch)
+		if err == nil {
+			done += len(batch)
+		}
+		batch = batch[:0]
+		if progress != nil {
+			progress(done)
+		}
+		return err
+	}
+	for cursor.Next(ctx) {
+		var movie embeddedMovie
+		if err := cursor.Decode(&movie); err != nil {
+			return done, err
+		}
+		if !force && !movie.stale(s.Embedder, textHash(MovieText(movie.Movie))) {
+			continue
+		}
+		batch = append(batch, movie)
+		if len(batch) == embedBatchSize {
+			if err := flush(); err != nil {
+				return done, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return done, err
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return done, err
+		}
+	}
+	return done, nil
+}
+
+// Refresh re-embeds a movie after it changed, logging instead of failing so
+// callers can treat it as best effort
+func (s *Store) Refresh(ctx context.Context, imdbID string) {
+	if _, err := s.EmbedMovie(ctx, imdbID); err != nil {
+		logger.ErrorContext(ctx, "embedding movie failed", "imdb_id", imdbID, "error", err)
+	}
+}
+
+func (s *Store) embed(ctx context.Context, movies []embeddedMovie) ([][]float32, error) {
+	texts := make([]string, len(movies))
+	for i, movie := range movies {
+		texts[i] = MovieText(movie.Movie)
+	}
+	vectors, err := s.Embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+
+	writes := make([]mongo.WriteModel, len(movies))
+	for i, movie := range movies {
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"imdb_id": movie.ImdbID}).
+			SetUpdate(bson.M{"$set": bson.M{
+				"embedding":       vectors[i],
+				"embedding_model": s.Embedder.Name(),
+				"embedding_hash":  textHash(texts[i]),
+			}})
+	}
+	if _, err := database.OpenCollection("movies", s.Client).BulkWrite(ctx, writes); err != nil {
+		return nil, err
+	}
+	invalidate(s.Embedder.Name())
+	return vectors, nil
+}
@@ -0,0 +1,47 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Handler reacts to an event in the process that published it
+type Handler func(ctx context.Context, event Event) error
+
+// DomainBus runs the handlers registered for an event type before passing
+// the event on to the streams. Handlers run once, on the instance the change
+// was made on, where the streams may hear of it on every instance.
+type DomainBus struct {
+	Bus
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewDomainBus wraps bus, which keeps serving the streams
+func NewDomainBus(bus Bus) *DomainBus {
+	return &DomainBus{Bus: bus, handlers: map[string][]Handler{}}
+}
+
+// On registers handler for events of the given types
+func (b *DomainBus) On(handler Handler, eventTypes ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, eventType := range eventTypes {
+		b.handlers[eventType] = append(b.handlers[eventType], handler)
+	}
+}
+
+// Publish runs the handlers for the event's type and then publishes it to
+// the streams. Failing handlers are logged and do not keep the event from
+// the streams or the other handlers.
+func (b *DomainBus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	handlers := b.handlers[event.Type]
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			logger.ErrorContext(ctx, "event handler failed", "type", event.Type, "event_id", event.ID, "error", err)
+		}
+	}
+	return b.Bus.Publish(ctx, event)
+}
@@ -0,0 +1,150 @@
+// Package events tells connected clients what changed, over the
+// Server-Sent Events stream of GET /events. An event goes to every client,
+// or with a UserID only to that user's streams. On a replica set events are
+// written to MongoDB and every instance picks them up from a change stream,
+// so clients hear of changes made through any instance. Otherwise they stay
+// in the process that published them. A DomainBus also runs handlers, such
+// as queueing webhook deliveries, on the instance an event is published on.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/logging"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Event types, Data holds the payload named next to each
+const (
+	MovieCreated      = "movie.created"      // MovieChange
+	MovieUpdated      = "movie.updated"      // MovieChange
+	RankingCompleted  = "ranking.completed"  // RankingResult, to the reviewer
+	GenreChanged      = "genre.changed"      // GenreChange
+	CatalogueReranked = "catalogue.reranked" // RerankChange
+	RoleChanged       = "user.role_changed"  // RoleChange, to the user
+)
+
+var logger = logging.For("events")
+
+type Event struct {
+	ID     string          `bson:"_id" json:"id"`
+	Type   string          `bson:"type" json:"type"`
+	UserID string          `bson:"user_id,omitempty" json:"-"`
+	Data   json.RawMessage `bson:"data" json:"data" swaggertype:"object"`
+	Time   time.Time       `bson:"time" json:"time"`
+}
+
+// New is an event of eventType carrying data, for userID alone or for
+// everyone when userID is empty
+func New(eventType, userID string, data any) (Event, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:     bson.NewObjectID().Hex(),
+		Type:   eventType,
+		UserID: userID,
+		Data:   encoded,
+		Time:   time.Now().UTC(),
+	}, nil
+}
+
+// reaches reports whether the event goes to the streams of userID
+func (e Event) reaches(userID string) bool {
+	return e.UserID == "" || e.UserID == userID
+}
+
+type MovieChange struct {
+	ImdbID  string `json:"imdb_id"`
+	Title   string `json:"title,omitempty"`
+	Version int64  `json:"version,omitempty"`
+}
+
+type RankingResult struct {
+	ImdbID       string `json:"imdb_id"`
+	RankingName  string `json:"ranking_name"`
+	RankingValue int    `json:"ranking_value"`
+	Version      int64  `json:"version"`
+}
+
+// GenreChange reports a genre that was created, renamed or deleted. Deleted
+// genres may name the genre that replaced them in movies.
+type GenreChange struct {
+	Action     string `json:"action"`
+	GenreID    int    `json:"genre_id"`
+	GenreName  string `json:"genre_name,omitempty"`
+	ReplacedBy int    `json:"replaced_by,omitempty"`
+}
+
+// RerankChange reports movies whose ranking changed together: the ones that
+// had a ranking which was updated or deleted, or every movie a re-rank
+// brought back in line with the scale. PreviousValue is the ranking they had
+// and RankingName and RankingValue the one they have now, none of them is set
+// for a re-rank.
+type RerankChange struct {
+	Action        string `json:"action"`
+	PreviousValue int    `json:"previous_value,omitempty"`
+	RankingName   string `json:"ranking_name,omitempty"`
+	RankingValue  int    `json:"ranking_value,omitempty"`
+	Movies        int64  `json:"movies"`
+}
+
+type RoleChange struct {
+	Role string `json:"role"`
+}
+
+// Bus delivers published events to the subscribed streams
+type Bus interface {
+	Publish(ctx context.Context, event Event) error
+	// Subscribe opens a stream for userID, replaying the events after
+	// lastEventID when they are still known
+	Subscribe(userID, lastEventID string) *Subscription
+}
+
+// FromEnv builds the bus named by EVENTS_BACKEND: auto (the default) uses
+// MongoDB change streams when connected to a replica set and memory
+// otherwise. The MongoDB bus watches until ctx ends.
+func FromEnv(ctx context.Context, client *mongo.Client) (Bus, error) {
+	backend := os.Getenv("EVENTS_BACKEND")
+	if backend == "" || backend == "auto" {
+		backend = "memory"
+		if ok, err := supportsChangeStreams(ctx, client); err != nil {
+			logger.WarnContext(ctx, "unable to tell whether MongoDB is a replica set, keeping events in memory", "error", err)
+		} else if ok {
+			backend = "mongodb"
+		}
+	}
+	switch backend {
+	case "memory":
+		return NewHub(), nil
+	case "mongodb":
+		bus, err := NewMongoBus(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		go bus.Watch(ctx)
+		return bus, nil
+	default:
+		return nil, fmt.Errorf("invalid EVENTS_BACKEND %q", backend)
+	}
+}
+
+// supportsChangeStreams reports whether the server is a replica set member
+// or a mongos, which change streams need
+func supportsChangeStreams(ctx context.Context, client *mongo.Client) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return false, err
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
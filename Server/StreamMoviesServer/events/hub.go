@@ -0,0 +1,102 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// replaySize is how many recent events are kept for clients resuming
+	// with Last-Event-ID
+	replaySize = 256
+	// streamBuffer is how many events a stream may fall behind before it is
+	// closed, the client then reconnects and catches up from the replay
+	streamBuffer = 64
+)
+
+// Hub hands events to the streams of this process. Published straight to
+// it, it is the bus for a single instance.
+type Hub struct {
+	mu            sync.Mutex
+	subscriptions map[*Subscription]struct{}
+	recent        []Event // oldest first
+}
+
+func NewHub() *Hub {
+	return &Hub{subscriptions: map[*Subscription]struct{}{}}
+}
+
+func (h *Hub) Publish(_ context.Context, event Event) error {
+	h.deliver(event)
+	return nil
+}
+
+func (h *Hub) Subscribe(userID, lastEventID string) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := &Subscription{hub: h, userID: userID, events: make(chan Event, replaySize+streamBuffer)}
+	if lastEventID != "" {
+		for i, event := range h.recent {
+			if event.ID != lastEventID {
+				continue
+			}
+			for _, missed := range h.recent[i+1:] {
+				if missed.reaches(userID) {
+					s.events <- missed
+				}
+			}
+			break
+		}
+	}
+	h.subscriptions[s] = struct{}{}
+	return s
+}
+
+// deliver sends event to every stream it reaches. Streams that fell too far
+// behind are closed rather than holding up the others.
+func (h *Hub) deliver(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.recent = append(h.recent, event)
+	if len(h.recent) > replaySize {
+		h.recent = h.recent[len(h.recent)-replaySize:]
+	}
+	for s := range h.subscriptions {
+		if !event.reaches(s.userID) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			logger.Warn("event stream fell behind, closing it", "user_id", s.userID)
+			h.remove(s)
+		}
+	}
+}
+
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subscriptions[s]; ok {
+		delete(h.subscriptions, s)
+		close(s.events)
+	}
+}
+
+// Subscription is one open stream
+type Subscription struct {
+	hub    *Hub
+	userID string
+	events chan Event
+}
+
+// Events is closed when the subscription is, or when it fell behind
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
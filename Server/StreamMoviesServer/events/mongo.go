@@ -0,0 +1,110 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	Collection  = "events"
+	expiryIndex = "events_expiry"
+	// retention only needs to cover instances catching up after a restart
+	// of their change stream
+	retention = time.Hour
+
+	minRetryWait = time.Second
+	maxRetryWait = 30 * time.Second
+
+	codeChangeStreamHistoryLost = 286
+)
+
+// MongoBus writes events to MongoDB, every instance watching the collection
+// hands them to its own streams
+type MongoBus struct {
+	*Hub
+	collection *mongo.Collection
+}
+
+// NewMongoBus also makes MongoDB drop events after an hour
+func NewMongoBus(ctx context.Context, client *mongo.Client) (*MongoBus, error) {
+	collection := database.OpenCollection(Collection, client)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "time", Value: 1}},
+		Options: options.Index().SetName(expiryIndex).SetExpireAfterSeconds(int32(retention.Seconds())),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MongoBus{Hub: NewHub(), collection: collection}, nil
+}
+
+// Publish reaches the streams once the change stream reports the event
+func (b *MongoBus) Publish(ctx context.Context, event Event) error {
+	_, err := b.collection.InsertOne(ctx, event)
+	return err
+}
+
+// Watch delivers the events inserted by any instance until ctx ends. Broken
+// change streams are resumed where they stopped, after a growing wait.
+func (b *MongoBus) Watch(ctx context.Context) {
+	var resumeToken bson.Raw
+	wait := minRetryWait
+	for {
+		token, err := b.watch(ctx, resumeToken, func() { wait = minRetryWait })
+		if ctx.Err() != nil {
+			return
+		}
+		if token != nil {
+			resumeToken = token
+		}
+		var serverErr mongo.ServerError
+		if errors.As(err, &serverErr) && serverErr.HasErrorCode(codeChangeStreamHistoryLost) {
+			// the events since the token are gone, carry on from now
+			resumeToken = nil
+		}
+		logger.WarnContext(ctx, "event change stream stopped, restarting", "error", err, "wait", wait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, maxRetryWait)
+	}
+}
+
+// watch follows the change stream from resumeToken until it fails, calling
+// started once it is open. It returns the token of the last event delivered.
+func (b *MongoBus) watch(ctx context.Context, resumeToken bson.Raw, started func()) (bson.Raw, error) {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": "insert"}}}}
+	opts := options.ChangeStream()
+	if resumeToken != nil {
+		opts.SetResumeAfter(resumeToken)
+	}
+	stream, err := b.collection.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close(context.Background())
+	started()
+
+	var last bson.Raw
+	for stream.Next(ctx) {
+		var change struct {
+			Event Event `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			logger.ErrorContext(ctx, "undecodable event skipped", "error", err)
+		} else {
+			b.deliver(change.Event)
+		}
+		last = slices.Clone(stream.ResumeToken())
+	}
+	return last, stream.Err()
+}
@@ -0,0 +1,139 @@
+// Package graph serves the catalogue over GraphQL. The schema lives in
+// schema.graphqls and the server is generated from it with go generate,
+// resolvers reuse the lookups and updates of the REST handlers.
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/logging"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/ratelimit"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var logger = logging.For("graph")
+
+const (
+	defaultMaxDepth      = 8
+	defaultMaxComplexity = 500
+)
+
+// Limits bound the operations the endpoint runs. Depth counts nested
+// selections, complexity counts fields with lists weighted by their size.
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+	Introspection bool
+}
+
+// LimitsFromEnv reads GRAPHQL_MAX_DEPTH, GRAPHQL_MAX_COMPLEXITY and
+// GRAPHQL_INTROSPECTION, which is on unless set to false
+func LimitsFromEnv() (Limits, error) {
+	limits := Limits{MaxDepth: defaultMaxDepth, MaxComplexity: defaultMaxComplexity, Introspection: true}
+	for _, p := range []struct {
+		name string
+		dest *int
+	}{
+		{"GRAPHQL_MAX_DEPTH", &limits.MaxDepth},
+		{"GRAPHQL_MAX_COMPLEXITY", &limits.MaxComplexity},
+	} {
+		if value := os.Getenv(p.name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return limits, fmt.Errorf("invalid %s %q", p.name, value)
+			}
+			*p.dest = parsed
+		}
+	}
+	if value := os.Getenv("GRAPHQL_INTROSPECTION"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return limits, fmt.Errorf("invalid GRAPHQL_INTROSPECTION %q", value)
+		}
+		limits.Introspection = enabled
+	}
+	return limits, nil
+}
+
+type ginContextKey struct{}
+
+// Handler godoc
+// @Summary GraphQL endpoint
+// @Description Run a GraphQL query or mutation against the schema in graph/schema.graphqls, with movies, genres, rankings, the signed in user and their recommendations, and mutations for reviews and the profile. Queries may also be sent with GET. Operations deeper than GRAPHQL_MAX_DEPTH or more complex than GRAPHQL_MAX_COMPLEXITY are refused. Errors carry the code and status of the matching REST error in their extensions.
+// @Tags graphql
+// @Accept  json
+// @Produce  json
+// @Param request body object true "query, operationName and variables"
+// @Success 200 {object} object
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 422 {object} object
+// @Router /graphql [post]
+func Handler(client *mongo.Client, limiter *ratelimit.Limiter, limits Limits) gin.HandlerFunc {
+	srv := handler.New(NewExecutableSchema(Config{
+		Resolvers:  &Resolver{Client: client, Limiter: limiter},
+		Complexity: complexity(),
+	}))
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	if limits.Introspection {
+		srv.Use(extension.Introspection{})
+	}
+	srv.Use(DepthLimit(limits.MaxDepth))
+	srv.Use(extension.FixedComplexityLimit(limits.MaxComplexity))
+	srv.SetErrorPresenter(presentError)
+	srv.SetRecoverFunc(func(ctx context.Context, recovered any) error {
+		logger.ErrorContext(ctx, "resolver panicked", "panic", recovered)
+		return apperr.Internal("Internal server error", nil)
+	})
+
+	return func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), ginContextKey{}, c)
+		ctx = withLoaders(ctx, client)
+		srv.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+	}
+}
+
+// ginContext returns the request's gin context, holding what AuthMiddleWare
+// found out about the user
+func ginContext(ctx context.Context) *gin.Context {
+	return ctx.Value(ginContextKey{}).(*gin.Context)
+}
+
+// presentError turns the *apperr.Error of a resolver into a GraphQL error
+// with the problem's code and status as extensions. Causes of server errors
+// only go to the log, like in the REST responses.
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	presented := graphql.DefaultErrorPresenter(ctx, err)
+	var appErr *apperr.Error
+	if !errors.As(err, &appErr) {
+		// raised by gqlgen itself, such as a query that failed validation
+		return presented
+	}
+	if appErr.Cause != nil && appErr.Status() >= http.StatusInternalServerError {
+		logger.ErrorContext(ctx, "graphql resolver failed", "path", presented.Path.String(), "code", appErr.Code, "error", appErr.Cause)
+	}
+	presented.Message = appErr.Message
+	presented.Extensions = map[string]any{"code": appErr.Code, "status": appErr.Status()}
+	for key, value := range appErr.Extensions {
+		presented.Extensions[key] = value
+	}
+	if len(appErr.Fields) > 0 {
+		presented.Extensions["errors"] = appErr.Fields
+	}
+	return presented
+}
@@ -0,0 +1,106 @@
+package graph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/graph/model"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	// savedListSize is what a saved list counts as towards complexity, its
+	// real size is only known once it was loaded
+	savedListSize = 20
+)
+
+const errDepthLimit = "DEPTH_LIMIT_EXCEEDED"
+
+// complexity weighs list fields by the number of items they can return, so
+// the complexity limit bounds the documents an operation loads
+func complexity() ComplexityRoot {
+	var c ComplexityRoot
+	c.Query.Movies = func(childComplexity int, _ *model.MovieFilter, first *int, _ *int) int {
+		return 1 + childComplexity*pageSize(first, defaultPageSize)
+	}
+	c.Query.Recommendations = func(childComplexity int, first *int) int {
+		return 1 + childComplexity*pageSize(first, defaultPageSize)
+	}
+	c.User.Watchlist = func(childComplexity int) int {
+		return 1 + childComplexity*savedListSize
+	}
+	c.User.Favourites = func(childComplexity int) int {
+		return 1 + childComplexity*savedListSize
+	}
+	return c
+}
+
+// pageSize is first bounded to 1 to maxPageSize, or fallback when unset
+func pageSize(first *int, fallback int) int {
+	if first == nil {
+		return fallback
+	}
+	return min(max(*first, 1), maxPageSize)
+}
+
+type depthLimit struct {
+	max int
+}
+
+// DepthLimit refuses operations with selections nested deeper than max.
+// Introspection is left out, its queries are deep by design and bounded by
+// the schema.
+func DepthLimit(max int) graphql.HandlerExtension {
+	return depthLimit{max: max}
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = depthLimit{}
+
+func (depthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+func (depthLimit) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (d depthLimit) MutateOperationContext(_ context.Context, oc *graphql.OperationContext) *gqlerror.Error {
+	if depth := selectionDepth(oc.Operation.SelectionSet); depth > d.max {
+		err := gqlerror.Errorf("operation has depth %d, more than the limit of %d", depth, d.max)
+		err.Extensions = map[string]any{"code": errDepthLimit}
+		return err
+	}
+	return nil
+}
+
+// selectionDepth is the number of fields on the longest path through set.
+// Fragments count as the fields they spread, validation already refused
+// fragments that spread themselves.
+func selectionDepth(set ast.SelectionSet) int {
+	deepest := 0
+	for _, selection := range set {
+		var depth int
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			depth = 1 + selectionDepth(s.SelectionSet)
+		case *ast.InlineFragment:
+			depth = selectionDepth(s.SelectionSet)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				depth = selectionDepth(s.Definition.SelectionSet)
+			}
+		}
+		deepest = max(deepest, depth)
+	}
+	return deepest
+}
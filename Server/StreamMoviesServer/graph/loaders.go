@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/controllers"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// loaderWait is how long a loader collects keys before querying, resolvers
+// of one level run concurrently and all get in well within it
+const loaderWait = 2 * time.Millisecond
+
+var errMovieNotFound = errors.New("movie not found")
+
+type loadersKey struct{}
+
+// loaders batch the lookups resolvers make per item, such as the movies of
+// a saved list or the movie count of every genre, into one query for all
+// items of the operation. They live for one request, so they cache freely.
+type loaders struct {
+	movies      *dataloader.Loader[string, models.Movie]
+	genreCounts *dataloader.Loader[int, int]
+}
+
+func withLoaders(ctx context.Context, client *mongo.Client) context.Context {
+	l := &loaders{
+		movies: dataloader.NewBatchedLoader(func(ctx context.Context, imdbIDs []string) []*dataloader.Result[models.Movie] {
+			byID, err := controllers.MoviesByID(ctx, client, imdbIDs)
+			results := make([]*dataloader.Result[models.Movie], len(imdbIDs))
+			for i, imdbID := range imdbIDs {
+				movie, ok := byID[imdbID]
+				switch {
+				case err != nil:
+					results[i] = &dataloader.Result[models.Movie]{Error: err}
+				case !ok:
+					results[i] = &dataloader.Result[models.Movie]{Error: errMovieNotFound}
+				default:
+					results[i] = &dataloader.Result[models.Movie]{Data: movie}
+				}
+			}
+			return results
+		}, dataloader.WithWait[string, models.Movie](loaderWait)),
+		// one aggregation counts all genres, whichever were asked for
+		genreCounts: dataloader.NewBatchedLoader(func(ctx context.Context, genreIDs []int) []*dataloader.Result[int] {
+			counts, err := controllers.GenreMovieCounts(ctx, client)
+			results := make([]*dataloader.Result[int], len(genreIDs))
+			for i, genreID := range genreIDs {
+				results[i] = &dataloader.Result[int]{Data: counts[genreID], Error: err}
+			}
+			return results
+		}, dataloader.WithWait[int, int](loaderWait)),
+	}
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFor(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// loadMovies returns the movies with imdbIDs in order, leaving out the ones
+// that no longer exist
+func loadMovies(ctx context.Context, imdbIDs []string) ([]models.Movie, error) {
+	found, errs := loadersFor(ctx).movies.LoadMany(ctx, imdbIDs)()
+	movies := make([]models.Movie, 0, len(found))
+	for i, movie := range found {
+		if errs != nil && errs[i] != nil {
+			if errors.Is(errs[i], errMovieNotFound) {
+				continue
+			}
+			return nil, errs[i]
+		}
+		movies = append(movies, movie)
+	}
+	return movies, nil
+}
+
+// loadMovie returns the movie with imdbID, nil when there is none
+func loadMovie(ctx context.Context, imdbID string) (*models.Movie, error) {
+	movie, err := loadersFor(ctx).movies.Load(ctx, imdbID)()
+	if errors.Is(err, errMovieNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &movie, nil
+}
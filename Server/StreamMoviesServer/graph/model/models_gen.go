@@ -0,0 +1,30 @@
+// Code generated by github.com/99designs/gqlgen, DO NOT EDIT.
+
+package model
+
+// The filters of GET /movies
+type MovieFilter struct {
+	// Genre names or ids
+	Genre         []string `json:"genre,omitempty"`
+	Ranking       []string `json:"ranking,omitempty"`
+	MaxRanking    *int     `json:"maxRanking,omitempty"`
+	Q             *string  `json:"q,omitempty"`
+	Year          *int     `json:"year,omitempty"`
+	YearFrom      *int     `json:"yearFrom,omitempty"`
+	YearTo        *int     `json:"yearTo,omitempty"`
+	Language      []string `json:"language,omitempty"`
+	Country       []string `json:"country,omitempty"`
+	Certification []string `json:"certification,omitempty"`
+	MinRuntime    *int     `json:"minRuntime,omitempty"`
+	MaxRuntime    *int     `json:"maxRuntime,omitempty"`
+	// person_id credited in cast or crew
+	Person *string `json:"person,omitempty"`
+	// cast, crew or a crew job such as Director
+	PersonRole *string `json:"personRole,omitempty"`
+}
+
+type Mutation struct {
+}
+
+type Query struct {
+}
@@ -0,0 +1,125 @@
+package graph
+
+//go:generate go run github.com/99designs/gqlgen generate --config gqlgen.yml
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/audit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/controllers"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/graph/model"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/logging"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/ratelimit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/utils"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Resolver serves the schema from the same lookups as the REST handlers
+type Resolver struct {
+	Client  *mongo.Client
+	Limiter *ratelimit.Limiter
+}
+
+// currentUser returns the id and role AuthMiddleWare set for the request
+func currentUser(ctx context.Context) (string, string, error) {
+	c := ginContext(ctx)
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		return "", "", apperr.Unauthenticated(err)
+	}
+	role, err := utils.GetRoleFromContext(c)
+	if err != nil {
+		return "", "", apperr.Unauthenticated(err)
+	}
+	return userID, role, nil
+}
+
+// filterValues turns filter into the query string of GET /movies, so both
+// are parsed and checked the same way
+func filterValues(filter *model.MovieFilter) url.Values {
+	values := url.Values{}
+	if filter == nil {
+		return values
+	}
+	values["genre"] = filter.Genre
+	values["ranking"] = filter.Ranking
+	values["language"] = filter.Language
+	values["country"] = filter.Country
+	values["certification"] = filter.Certification
+	for name, value := range map[string]*int{
+		"max_ranking": filter.MaxRanking,
+		"year":        filter.Year,
+		"year_from":   filter.YearFrom,
+		"year_to":     filter.YearTo,
+		"min_runtime": filter.MinRuntime,
+		"max_runtime": filter.MaxRuntime,
+	} {
+		if value != nil {
+			values.Set(name, strconv.Itoa(*value))
+		}
+	}
+	for name, value := range map[string]*string{
+		"q":           filter.Q,
+		"person":      filter.Person,
+		"person_role": filter.PersonRole,
+	} {
+		if value != nil {
+			values.Set(name, *value)
+		}
+	}
+	return values
+}
+
+// audited runs change and records it in the audit log, as the Audit
+// middleware does for the REST route of the same action
+func (r *Resolver) audited(ctx context.Context, action, collection, key, id string, change func() error) error {
+	c := ginContext(ctx)
+	before, err := audit.Snapshot(ctx, r.Client, collection, key, id)
+	if err != nil {
+		logger.ErrorContext(ctx, "audit snapshot failed", "action", action, "collection", collection, "error", err)
+	}
+	if err := change(); err != nil {
+		return err
+	}
+
+	entry := audit.Entry{
+		ActorID:    c.GetString("userID"),
+		ActorRole:  c.GetString("role"),
+		Action:     action,
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		Status:     http.StatusOK,
+		RequestID:  logging.RequestID(c),
+		IP:         c.ClientIP(),
+		TargetType: collection,
+		TargetID:   id,
+	}
+	// the request context ends with the response, the entry must not
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	after, err := audit.Snapshot(recordCtx, r.Client, collection, key, id)
+	if err != nil {
+		logger.ErrorContext(ctx, "audit snapshot failed", "action", action, "collection", collection, "error", err)
+	}
+	entry.Changes = audit.Diff(before, after)
+	if err := audit.Record(recordCtx, r.Client, entry); err != nil {
+		logger.ErrorContext(ctx, "recording audit entry failed", "action", action, "error", err)
+	}
+	return nil
+}
+
+// savedMovies returns the movies on one of user's lists. Both lists of an
+// operation are loaded with one query.
+func (r *userResolver) savedMovies(ctx context.Context, user *models.User, list string) ([]models.Movie, error) {
+	imdbIDs, err := controllers.SavedMovieIDs(ctx, r.Client, user.UserID, list)
+	if err != nil {
+		return nil, err
+	}
+	return loadMovies(ctx, imdbIDs)
+}
@@ -0,0 +1,191 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.81
+
+import (
+	"context"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/controllers"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/graph/model"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/middlewares"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/validation"
+)
+
+// MovieCount is the resolver for the movieCount field.
+func (r *genreResolver) MovieCount(ctx context.Context, obj *models.Genre) (int, error) {
+	return loadersFor(ctx).genreCounts.Load(ctx, obj.GenreID)()
+}
+
+// UpdatedAt is the resolver for the updatedAt field.
+func (r *movieResolver) UpdatedAt(ctx context.Context, obj *models.Movie) (*time.Time, error) {
+	if obj.UpdatedAt.IsZero() {
+		return nil, nil
+	}
+	return &obj.UpdatedAt, nil
+}
+
+// UpdateReview is the resolver for the updateReview field.
+func (r *mutationResolver) UpdateReview(ctx context.Context, imdbID string, review string, ifMatch *string) (*controllers.ReviewUpdate, error) {
+	userID, role, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if role != "ADMIN" && role != "USER" {
+		return nil, apperr.Forbidden("forbidden", "User must be admin or user")
+	}
+	if !validation.IsImdbID(imdbID) {
+		return nil, apperr.InvalidParameter("Invalid imdbId, expected an IMDb id such as tt0111161")
+	}
+	if err := validation.Struct(models.UpdateReview{AdminReview: review}); err != nil {
+		return nil, apperr.Validation(err)
+	}
+	policy, _ := r.Limiter.Policy("review")
+	if err := middlewares.CheckRateLimit(ginContext(ctx), r.Limiter, policy); err != nil {
+		return nil, err
+	}
+	var match string
+	if ifMatch != nil {
+		match = *ifMatch
+	}
+
+	var updated controllers.ReviewUpdate
+	err = r.audited(ctx, "movie.review", "movies", "imdb_id", imdbID, func() error {
+		updated, err = controllers.UpdateReview(ctx, r.Client, userID, imdbID, review, match)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// UpdateProfile is the resolver for the updateProfile field.
+func (r *mutationResolver) UpdateProfile(ctx context.Context, input models.UpdateUser) (*models.User, error) {
+	userID, _, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validation.Struct(input); err != nil {
+		return nil, apperr.Validation(err)
+	}
+	user, err := controllers.UpdateProfile(ctx, r.Client, userID, input)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Movies is the resolver for the movies field.
+func (r *queryResolver) Movies(ctx context.Context, filter *model.MovieFilter, first *int, skip *int) ([]models.Movie, error) {
+	parsed, err := catalogue.ParseMovieFilter(filterValues(filter))
+	if err != nil {
+		return nil, apperr.InvalidParameter(err.Error())
+	}
+	var offset int64
+	if skip != nil {
+		if *skip < 0 {
+			return nil, apperr.InvalidParameter("skip must not be negative")
+		}
+		offset = int64(*skip)
+	}
+	return controllers.FindMovies(ctx, r.Client, parsed, offset, int64(pageSize(first, defaultPageSize)))
+}
+
+// Movie is the resolver for the movie field.
+func (r *queryResolver) Movie(ctx context.Context, imdbID string) (*models.Movie, error) {
+	if !validation.IsImdbID(imdbID) {
+		return nil, apperr.InvalidParameter("Invalid imdbId, expected an IMDb id such as tt0111161")
+	}
+	movie, found, err := controllers.FindMovie(ctx, r.Client, imdbID)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &movie, nil
+}
+
+// Genres is the resolver for the genres field.
+func (r *queryResolver) Genres(ctx context.Context) ([]models.Genre, error) {
+	return controllers.Genres(ctx, r.Client)
+}
+
+// Rankings is the resolver for the rankings field.
+func (r *queryResolver) Rankings(ctx context.Context) ([]models.Ranking, error) {
+	return controllers.Rankings(ctx, r.Client)
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*models.User, error) {
+	userID, _, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := controllers.FindUser(ctx, r.Client, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Recommendations is the resolver for the recommendations field.
+func (r *queryResolver) Recommendations(ctx context.Context, first *int) ([]models.RecommendedMovie, error) {
+	userID, _, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	limit := controllers.RecommendationLimit(ctx)
+	if first != nil {
+		limit = int64(pageSize(first, 0))
+	}
+	return controllers.RecommendedMovies(ctx, r.Client, userID, limit)
+}
+
+// Movie is the resolver for the movie field.
+func (r *recommendationReasonResolver) Movie(ctx context.Context, obj *models.RecommendationReason) (*models.Movie, error) {
+	if obj.ImdbID == "" {
+		return nil, nil
+	}
+	return loadMovie(ctx, obj.ImdbID)
+}
+
+// Watchlist is the resolver for the watchlist field.
+func (r *userResolver) Watchlist(ctx context.Context, obj *models.User) ([]models.Movie, error) {
+	return r.savedMovies(ctx, obj, controllers.WatchlistCollection)
+}
+
+// Favourites is the resolver for the favourites field.
+func (r *userResolver) Favourites(ctx context.Context, obj *models.User) ([]models.Movie, error) {
+	return r.savedMovies(ctx, obj, controllers.FavouritesCollection)
+}
+
+// Genre returns GenreResolver implementation.
+func (r *Resolver) Genre() GenreResolver { return &genreResolver{r} }
+
+// Movie returns MovieResolver implementation.
+func (r *Resolver) Movie() MovieResolver { return &movieResolver{r} }
+
+// Mutation returns MutationResolver implementation.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+// RecommendationReason returns RecommendationReasonResolver implementation.
+func (r *Resolver) RecommendationReason() RecommendationReasonResolver {
+	return &recommendationReasonResolver{r}
+}
+
+// User returns UserResolver implementation.
+func (r *Resolver) User() UserResolver { return &userResolver{r} }
+
+type genreResolver struct{ *Resolver }
+type movieResolver struct{ *Resolver }
+type mutationResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type recommendationReasonResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
@@ -0,0 +1,107 @@
+// Package llm is a small seam over the language model used to interpret free
+// text, so handlers can run against OpenAI or a deterministic fake
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+const defaultOpenAIModel = "gpt-4o-mini"
+
+var ErrNotConfigured = errors.New("language model is not configured")
+
+// Model answers a prompt following the instructions in system. JSON asks for
+// a reply that is a single JSON object.
+type Model interface {
+	Generate(ctx context.Context, system, prompt string, json bool) (string, error)
+}
+
+// NewModelFromEnv picks the model named by LLM_PROVIDER: "openai" (the
+// default) with OPENAI_API_KEY and LLM_MODEL, or "fake", which replays the
+// prompt to reply map stored as JSON in the file LLM_FAKE_RESPONSES
+func NewModelFromEnv() (Model, error) {
+	switch strings.ToLower(os.Getenv("LLM_PROVIDER")) {
+	case "", "openai":
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" {
+			return nil, ErrNotConfigured
+		}
+		return NewOpenAI(apiKey, os.Getenv("LLM_MODEL"))
+	case "fake":
+		return LoadFake(os.Getenv("LLM_FAKE_RESPONSES"))
+	default:
+		return nil, fmt.Errorf("unknown LLM_PROVIDER %q, use openai or fake", os.Getenv("LLM_PROVIDER"))
+	}
+}
+
+type OpenAI struct {
+	llm *openai.LLM
+}
+
+func NewOpenAI(apiKey, model string) (*OpenAI, error) {
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	client, err := openai.New(openai.WithToken(apiKey), openai.WithModel(model))
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAI{llm: client}, nil
+}
+
+func (o *OpenAI) Generate(ctx context.Context, system, prompt string, json bool) (string, error) {
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, system),
+		llms.TextParts(llms.ChatMessageTypeHuman, prompt),
+	}
+	options := []llms.CallOption{llms.WithTemperature(0)}
+	if json {
+		options = append(options, llms.WithJSONMode())
+	}
+	response, err := o.llm.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return "", err
+	}
+	if len(response.Choices) == 0 {
+		return "", errors.New("language model returned no choices")
+	}
+	return response.Choices[0].Content, nil
+}
+
+// Fake answers from a fixed map of prompts to replies and ignores the system
+// instructions, so the same prompt always gets the same reply
+type Fake struct {
+	Responses map[string]string
+}
+
+// LoadFake reads the replies of a Fake from a JSON object file
+func LoadFake(path string) (*Fake, error) {
+	if path == "" {
+		return &Fake{Responses: map[string]string{}}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fake := &Fake{}
+	if err := json.Unmarshal(data, &fake.Responses); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return fake, nil
+}
+
+func (f *Fake) Generate(_ context.Context, _, prompt string, _ bool) (string, error) {
+	response, ok := f.Responses[prompt]
+	if !ok {
+		return "", fmt.Errorf("fake model has no reply for %q", prompt)
+	}
+	return response, nil
+}
@@ -0,0 +1,167 @@
+// Package logging sets up structured JSON logging with log/slog. Every
+// package logs through its own logger from For so its level can be set on
+// its own, lines logged with a request context carry the request id and
+// secrets, tokens and email addresses are redacted before they are written.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// config is what Setup read from the environment, package loggers look it up
+// on every call so loggers created before Setup pick it up
+type config struct {
+	handler slog.Handler
+	level   slog.Level
+	levels  map[string]slog.Level
+}
+
+var current atomic.Pointer[config]
+
+func init() {
+	current.Store(&config{handler: newHandler(os.Stderr, "json"), level: slog.LevelInfo})
+}
+
+// Setup configures logging from LOG_LEVEL (debug, info, warn or error,
+// default info), LOG_LEVELS with per package overrides such as
+// "recommender=debug,embeddings=warn" and LOG_FORMAT (json or text). It also
+// routes slog.Default and the standard log package, which libraries write
+// to, through the same handler.
+func Setup() error {
+	cfg := &config{levels: map[string]slog.Level{}}
+	if err := cfg.level.UnmarshalText([]byte(envOr("LOG_LEVEL", "info"))); err != nil {
+		return fmt.Errorf("invalid LOG_LEVEL: %w", err)
+	}
+	for _, override := range strings.Split(os.Getenv("LOG_LEVELS"), ",") {
+		if strings.TrimSpace(override) == "" {
+			continue
+		}
+		pkg, value, ok := strings.Cut(override, "=")
+		var level slog.Level
+		if !ok || level.UnmarshalText([]byte(strings.TrimSpace(value))) != nil {
+			return fmt.Errorf("invalid LOG_LEVELS entry %q", override)
+		}
+		cfg.levels[strings.TrimSpace(pkg)] = level
+	}
+	format := envOr("LOG_FORMAT", "json")
+	if format != "json" && format != "text" {
+		return fmt.Errorf("invalid LOG_FORMAT %q", format)
+	}
+	cfg.handler = newHandler(os.Stderr, format)
+	current.Store(cfg)
+	slog.SetDefault(For("main"))
+	return nil
+}
+
+func newHandler(w io.Writer, format string) slog.Handler {
+	// levels are decided per package by packageHandler, the handler itself
+	// lets everything through
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: redactAttr}
+	if format == "text" {
+		return slog.NewTextHandler(w, opts)
+	}
+	return slog.NewJSONHandler(w, opts)
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// For returns the logger of a package, tagged with its name
+func For(pkg string) *slog.Logger {
+	return slog.New(&packageHandler{pkg: pkg}).With("package", pkg)
+}
+
+// packageHandler applies the level of its package and adds the request and
+// trace ids before handing records to the configured handler
+type packageHandler struct {
+	pkg string
+	// with replays WithAttrs and WithGroup calls, in order, on the handler
+	// configured at the time of logging
+	with   []func(slog.Handler) slog.Handler
+	cached atomic.Pointer[cachedHandler]
+}
+
+// cachedHandler is the result of replaying with on the handler of cfg
+type cachedHandler struct {
+	cfg     *config
+	handler slog.Handler
+}
+
+func (h *packageHandler) Enabled(_ context.Context, level slog.Level) bool {
+	cfg := current.Load()
+	minimum, ok := cfg.levels[h.pkg]
+	if !ok {
+		minimum = cfg.level
+	}
+	return level >= minimum
+}
+
+func (h *packageHandler) Handle(ctx context.Context, record slog.Record) error {
+	cfg := current.Load()
+	cached := h.cached.Load()
+	if cached == nil || cached.cfg != cfg {
+		handler := cfg.handler
+		for _, with := range h.with {
+			handler = with(handler)
+		}
+		cached = &cachedHandler{cfg: cfg, handler: handler}
+		h.cached.Store(cached)
+	}
+	handler := cached.handler
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return handler.Handle(ctx, record)
+}
+
+func (h *packageHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.add(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *packageHandler) WithGroup(name string) slog.Handler {
+	return h.add(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h *packageHandler) add(with func(slog.Handler) slog.Handler) slog.Handler {
+	return &packageHandler{pkg: h.pkg, with: append(h.with[:len(h.with):len(h.with)], with)}
+}
+
+type requestIDKey struct{}
+
+// RequestIDContextKey is the gin context key the request id is kept under,
+// gin contexts answer Value lookups for it from their keys
+const RequestIDContextKey = "request_id"
+
+// WithRequestID returns a copy of ctx carrying the request id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request id carried by ctx, or an empty string
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	if id, ok := ctx.Value(RequestIDContextKey).(string); ok {
+		return id
+	}
+	return ""
+}
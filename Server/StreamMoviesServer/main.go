@@ -2,8 +2,8 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -11,14 +11,29 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/audit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/cache"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/commands"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/controllers"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
 	_ "github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/docs"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/events"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/graph"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/logging"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/metrics"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/middlewares"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/migrations"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/ratelimit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/recommender"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/routes"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/tracing"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/utils"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/webhooks"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // @title Movie Stream API
@@ -36,57 +51,140 @@ import (
 // @host localhost:8080
 // @BasePath /api/v1
 func main() {
-	router := gin.Default()
-
-	router.GET("/healthcheck", func(c *gin.Context) {
-		c.String(200, "ok")
-	})
+	if len(os.Args) > 1 {
+		os.Exit(commands.Run(os.Args[1:]))
+	}
 
 	err := godotenv.Load(".env")
+	if err := logging.Setup(); err != nil {
+		slog.Error("invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
 	if err != nil {
-		log.Println("Warning: unable to find .env file")
+		slog.Warn("unable to find .env file")
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		slog.Error("invalid tracing configuration", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("flushing traces failed", "error", err)
+		}
+	}()
+
+	router := gin.New()
+	// handlers pass the gin context on as their context, fall back to the
+	// request context so the request span and id reach database calls
+	router.ContextWithFallback = true
+	// client addresses key rate limits, only take them from X-Forwarded-For
+	// when the request came through one of TRUSTED_PROXIES
+	if err := router.SetTrustedProxies(splitList(os.Getenv("TRUSTED_PROXIES"))); err != nil {
+		slog.Error("invalid TRUSTED_PROXIES", "error", err)
+		os.Exit(1)
+	}
+	router.Use(
+		middlewares.RequestID(),
+		otelgin.Middleware(tracing.ServiceName, otelgin.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/metrics" && r.URL.Path != "/healthcheck"
+		})),
+		middlewares.AccessLog(),
+		middlewares.Metrics(),
+		middlewares.Errors(),
+		middlewares.Recovery(),
+	)
+
+	router.GET("/healthcheck", func(c *gin.Context) {
+		c.String(200, "ok")
+	})
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	router.NoRoute(func(c *gin.Context) {
+		c.Error(apperr.NotFound("route_not_found", "Route not found"))
+	})
+
 	controllers.InitGoogleOAuth()
 
 	allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
 
-	var origins []string
-	if allowedOrigins != "" {
-		origins = strings.Split(allowedOrigins, ",")
-		for i := range origins {
-			origins[i] = strings.TrimSpace(origins[i])
-			log.Println("Allowed Origin:", origins[i])
-		}
-	} else {
+	origins := splitList(allowedOrigins)
+	if len(origins) == 0 {
 		origins = []string{"http://localhost:5173"}
-		log.Println("Allowed Origin: http://localhost:5173")
 	}
+	slog.Info("allowed origins", "origins", origins)
 
 	config := cors.Config{}
 	config.AllowOrigins = origins
 	config.AllowMethods = []string{"GET", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"}
 	// config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
-	config.ExposeHeaders = []string{"Content-Length"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", middlewares.RequestIDHeader, "If-Match", "If-None-Match", "If-Modified-Since", "Last-Event-ID"}
+	config.ExposeHeaders = append([]string{"Content-Length", "ETag", "Last-Modified", middlewares.RequestIDHeader}, middlewares.RateLimitHeaders...)
 	config.AllowCredentials = true
 	config.MaxAge = 12 * time.Hour
 
 	router.Use(cors.New(config))
-	router.Use(gin.Logger())
 
 	client := database.Connect()
 
 	if err := client.Ping(context.Background(), nil); err != nil {
-		log.Fatalf("Failed to reach server: %v", err)
+		slog.Error("failed to reach MongoDB", "error", err)
+		os.Exit(1)
 	}
 	defer func() {
 		err := client.Disconnect(context.Background())
 		if err != nil {
-			log.Fatalf("Failed to disconnect from MongoDB: %v", err)
+			slog.Error("failed to disconnect from MongoDB", "error", err)
 		}
 	}()
-	mailChan := make(chan models.MailData)
+	if err := migrations.EnsureIndexes(context.Background(), client); err != nil {
+		slog.Error("unable to create indexes, run the migrate command for details", "error", err)
+		os.Exit(1)
+	}
+	if err := controllers.EnsureHistoryRetention(context.Background(), client); err != nil {
+		slog.Warn("unable to apply watch history retention", "error", err)
+	}
+	if err := audit.EnsureRetention(context.Background(), client); err != nil {
+		slog.Warn("unable to apply audit log retention", "error", err)
+	}
+	hotCache, cacheTTL, err := cache.FromEnv()
+	if err != nil {
+		slog.Error("invalid cache configuration", "error", err)
+		os.Exit(1)
+	}
+	controllers.InitCache(hotCache, cacheTTL)
+	eventBus, err := events.FromEnv(context.Background(), client)
+	if err != nil {
+		slog.Error("unable to set up events", "error", err)
+		os.Exit(1)
+	}
+	webhookConfig, err := webhooks.ConfigFromEnv()
+	if err != nil {
+		slog.Error("invalid webhook configuration", "error", err)
+		os.Exit(1)
+	}
+	if err := webhooks.EnsureIndexes(context.Background(), client); err != nil {
+		slog.Warn("unable to index webhook deliveries", "error", err)
+	}
+	// webhooks are queued once, by the instance the change was made on
+	webhookWorker := webhooks.NewWorker(client, webhookConfig)
+	domainBus := events.NewDomainBus(eventBus)
+	domainBus.On(webhookWorker.Enqueue, webhooks.Events...)
+	controllers.InitEvents(domainBus)
+	controllers.InitWebhooks(webhookWorker)
+	go webhookWorker.Start(context.Background())
+	if interval, err := recommender.IntervalFromEnv(); err != nil {
+		slog.Warn("recommendation job disabled", "error", err)
+	} else if interval > 0 {
+		engine := recommender.NewEngine(client)
+		engine.Rebuilt = controllers.ForgetRecommendations
+		go engine.Start(context.Background(), interval)
+	}
+	// buffered so reset requests do not wait on the mail server, the backlog
+	// is reported as mail_queue_length
+	mailChan := make(chan models.MailData, 100)
 	defer close(mailChan)
 	utils.ListenForMail(mailChan)
 	// msg := models.MailData{
@@ -98,12 +196,40 @@ func main() {
 	// }
 	// mailChan <- msg
 
-	routes.SetupUnProtectedRoutes(router, client, mailChan)
-	routes.SetupProtectedRoutes(router, client)
+	policies, err := ratelimit.PoliciesFromEnv()
+	if err != nil {
+		slog.Error("invalid rate limit configuration", "error", err)
+		os.Exit(1)
+	}
+	store, err := ratelimit.StoreFromEnv(context.Background(), client)
+	if err != nil {
+		slog.Error("unable to set up rate limit store", "error", err)
+		os.Exit(1)
+	}
+	limiter := ratelimit.NewLimiter(store, policies)
+	graphLimits, err := graph.LimitsFromEnv()
+	if err != nil {
+		slog.Error("invalid GraphQL configuration", "error", err)
+		os.Exit(1)
+	}
+
+	routes.SetupUnProtectedRoutes(router, client, mailChan, limiter)
+	routes.SetupProtectedRoutes(router, client, limiter, graphLimits)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	if err := router.Run(":8080"); err != nil {
-		fmt.Println("Failed to start server", err)
+		slog.Error("failed to start server", "error", err)
+	}
+}
+
+// splitList splits a comma separated setting, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
@@ -0,0 +1,243 @@
+// Package metrics defines the Prometheus metrics of the server. Labels are
+// kept to route templates, command names and outcomes so their number stays
+// bounded: user, movie and request ids never become labels.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "moviestream"
+
+// Outcomes used as the outcome label
+const (
+	Success = "success"
+	Failure = "failure"
+	Error   = "error"
+
+	// Unmatched is the outcome of a review the language model gave a
+	// ranking name that is not on the scale
+	Unmatched = "unmatched"
+
+	// RateAllowed and RateLimited are the outcomes of rate limit checks
+	RateAllowed = "allowed"
+	RateLimited = "limited"
+
+	// CacheHit and CacheMiss are the outcomes of cache lookups
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route template, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to handle HTTP requests, by route template and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	httpInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests being handled.",
+	})
+
+	mongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongodb_command_duration_seconds",
+		Help:      "Time taken by MongoDB commands, by command name and outcome.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"command", "outcome"})
+
+	reviewRankings = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "review_rankings_total",
+		Help:      "Admin reviews ranked by the language model, by outcome: success, unmatched or error.",
+	}, []string{"outcome"})
+
+	llmDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "Time taken by language model calls, by operation and outcome.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 20, 30, 60},
+	}, []string{"operation", "outcome"})
+
+	mailMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mail_messages_total",
+		Help:      "Emails taken off the mail queue, by status: sent or failed.",
+	}, []string{"status"})
+
+	authAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_attempts_total",
+		Help:      "Authentication attempts, by method (password, google, refresh or token) and outcome.",
+	}, []string{"method", "outcome"})
+
+	rateLimitDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_decisions_total",
+		Help:      "Requests checked against a rate limit policy, by policy and outcome: allowed, limited or error.",
+	}, []string{"policy", "outcome"})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Cache lookups, by kind of value (genres, rankings, movie or recommendations) and outcome: hit, miss or error.",
+	}, []string{"kind", "outcome"})
+
+	eventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_published_total",
+		Help:      "Events published to clients, by type and outcome: success or error.",
+	}, []string{"type", "outcome"})
+
+	eventStreams = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "event_streams_open",
+		Help:      "Server-Sent Event streams open on this instance.",
+	})
+
+	webhookDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "webhook_attempt_duration_seconds",
+		Help:      "Time taken by webhook POSTs, by event type and outcome: success, or failure for errors and non-2xx answers.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"type", "outcome"})
+
+	webhooksFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_failed_total",
+		Help:      "Webhook deliveries given up on after their last attempt, by event type.",
+	}, []string{"type"})
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RequestStarted counts a request as in flight until the returned func is
+// called with its route template and status
+func RequestStarted(method string) func(route string, status int) {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+	default:
+		method = "OTHER"
+	}
+	started := time.Now()
+	httpInFlight.Inc()
+	return func(route string, status int) {
+		httpInFlight.Dec()
+		httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(route, method).Observe(time.Since(started).Seconds())
+	}
+}
+
+// MongoCommand records a finished MongoDB command
+func MongoCommand(command string, duration time.Duration, failed bool) {
+	mongoDuration.WithLabelValues(command, outcome(failed)).Observe(duration.Seconds())
+}
+
+// ReviewRanking records a review ranked by the language model, outcome is
+// Success, Unmatched or Error
+func ReviewRanking(outcome string, duration time.Duration) {
+	reviewRankings.WithLabelValues(outcome).Inc()
+	LLMRequest("review_ranking", outcome, duration)
+}
+
+// LLMRequest records a language model call made for operation
+func LLMRequest(operation, outcome string, duration time.Duration) {
+	llmDuration.WithLabelValues(operation, outcome).Observe(duration.Seconds())
+}
+
+// MailSent records an email taken off the queue
+func MailSent(failed bool) {
+	status := "sent"
+	if failed {
+		status = "failed"
+	}
+	mailMessages.WithLabelValues(status).Inc()
+}
+
+// MailQueue reports the number of emails waiting to be sent, read from
+// length whenever metrics are scraped
+func MailQueue(length func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mail_queue_length",
+		Help:      "Emails waiting to be sent.",
+	}, func() float64 { return float64(length()) })
+}
+
+// AuthAttempt records an authentication attempt, outcome is Success, Failure
+// when the credentials were refused or Error when they could not be checked
+func AuthAttempt(method, outcome string) {
+	authAttempts.WithLabelValues(method, outcome).Inc()
+}
+
+// RateLimit records a request checked against policy, outcome is
+// RateAllowed, RateLimited or Error when the store could not be reached
+func RateLimit(policy, outcome string) {
+	rateLimitDecisions.WithLabelValues(policy, outcome).Inc()
+}
+
+// CacheLookup records a cache lookup for a kind of value, outcome is
+// CacheHit, CacheMiss or Error
+func CacheLookup(kind, outcome string) {
+	cacheLookups.WithLabelValues(kind, outcome).Inc()
+}
+
+// EventPublished records publishing an event, outcome is Success or Error
+func EventPublished(eventType, outcome string) {
+	eventsPublished.WithLabelValues(eventType, outcome).Inc()
+}
+
+// EventStreamOpened and EventStreamClosed track the open event streams
+func EventStreamOpened() {
+	eventStreams.Inc()
+}
+
+func EventStreamClosed() {
+	eventStreams.Dec()
+}
+
+// WebhookAttempt records a webhook POST, outcome is Success or Failure
+func WebhookAttempt(eventType, outcome string, duration time.Duration) {
+	webhookDuration.WithLabelValues(eventType, outcome).Observe(duration.Seconds())
+}
+
+// WebhookFailed records a webhook delivery that ran out of attempts
+func WebhookFailed(eventType string) {
+	webhooksFailed.WithLabelValues(eventType).Inc()
+}
+
+// StatusOutcome maps a response status to Success, Failure (4xx) or Error (5xx)
+func StatusOutcome(status int) string {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return Error
+	case status >= http.StatusBadRequest:
+		return Failure
+	}
+	return Success
+}
+
+func outcome(failed bool) string {
+	if failed {
+		return Error
+	}
+	return Success
+}
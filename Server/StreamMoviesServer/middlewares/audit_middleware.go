@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/audit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/logging"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// AuditTarget names the document a route changes: the Collection it lives in
+// and the Key field identifying it, read from the route parameter Param.
+// Int converts the parameter for numeric keys such as genre_id. The zero
+// value is for bulk actions without a single target.
+type AuditTarget struct {
+	Collection string
+	Key        string
+	Param      string
+	Int        bool
+}
+
+// Audit must run after AuthMiddleWare. It snapshots the target before the
+// handler and records the action with the difference once the handler
+// succeeded. Handlers that create or re-key a document set its key under
+// audit.TargetContextKey.
+func Audit(client *mongo.Client, action string, target AuditTarget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var key any
+		var before bson.M
+		if target.Collection != "" && target.Param != "" {
+			key = c.Param(target.Param)
+			if target.Int {
+				if value, err := strconv.Atoi(c.Param(target.Param)); err == nil {
+					key = value
+				} else {
+					key = nil
+				}
+			}
+			if key != nil {
+				var err error
+				if before, err = audit.Snapshot(c, client, target.Collection, target.Key, key); err != nil {
+					logger.ErrorContext(c, "audit snapshot failed", "action", action, "collection", target.Collection, "error", err)
+				}
+			}
+		}
+
+		c.Next()
+
+		status := ResponseStatus(c)
+		if status >= 400 {
+			return
+		}
+		if value, ok := c.Get(audit.TargetContextKey); ok {
+			key = value
+		}
+		entry := audit.Entry{
+			ActorID:   c.GetString("userID"),
+			ActorRole: c.GetString("role"),
+			Action:    action,
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Status:    status,
+			RequestID: logging.RequestID(c),
+			IP:        c.ClientIP(),
+		}
+		// the request context ends with the response, the entry must not
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if target.Collection != "" && key != nil {
+			entry.TargetType = target.Collection
+			entry.TargetID = fmt.Sprint(key)
+			after, err := audit.Snapshot(ctx, client, target.Collection, target.Key, key)
+			if err != nil {
+				logger.ErrorContext(c, "audit snapshot failed", "action", action, "collection", target.Collection, "error", err)
+			}
+			entry.Changes = audit.Diff(before, after)
+		}
+		if err := audit.Record(ctx, client, entry); err != nil {
+			logger.ErrorContext(c, "recording audit entry failed", "action", action, "error", err)
+		}
+	}
+}
@@ -2,9 +2,9 @@
 package middlewares
 
 import (
-	"net/http"
-
 	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/metrics"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/utils"
 )
 
@@ -12,24 +12,46 @@ func AuthMiddleWare() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token, err := utils.GetAccessToken(c)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			metrics.AuthAttempt("token", metrics.Failure)
+			c.Error(apperr.Unauthenticated(err))
 			c.Abort()
 			return
 		}
 		if token == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "No token provided"})
+			metrics.AuthAttempt("token", metrics.Failure)
+			c.Error(apperr.Unauthorized(apperr.CodeUnauthenticated, "No token provided"))
 			c.Abort()
 			return
 		}
 		claims, err := utils.ValidateToken(token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			metrics.AuthAttempt("token", metrics.Failure)
+			c.Error(apperr.Unauthorized("invalid_token", "Invalid token").WithCause(err))
 			c.Abort()
 			return
 		}
 		c.Set("userID", claims.UserID)
 		c.Set("role", claims.Role)
+		metrics.AuthAttempt("token", metrics.Success)
 
 		c.Next()
 	}
 }
+
+// AdminMiddleWare must run after AuthMiddleWare and lets only ADMIN users through
+func AdminMiddleWare() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.Error(apperr.Unauthenticated(err))
+			c.Abort()
+			return
+		}
+		if role != "ADMIN" {
+			c.Error(apperr.Forbidden("admin_required", "Admin access required"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,12 @@
+package middlewares
+
+import "github.com/gin-gonic/gin"
+
+// CacheControl sets the Cache-Control policy of a route. Problem responses
+// replace it with no-store so errors are never cached.
+func CacheControl(policy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", policy)
+		c.Next()
+	}
+}
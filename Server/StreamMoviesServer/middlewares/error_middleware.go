@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/logging"
+)
+
+const problemContentType = "application/problem+json"
+
+// Errors answers requests whose handler reported an error with c.Error, and
+// wrote nothing, with a problem document for the last error. Causes are left
+// to the access log.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		WriteProblem(c, c.Errors.Last().Err)
+	}
+}
+
+// ResponseStatus is the status the request is answered with. Middleware
+// running inside Errors sees the handler return before the problem for an
+// error reported with c.Error is written, so that error's status counts.
+func ResponseStatus(c *gin.Context) int {
+	if len(c.Errors) > 0 && !c.Writer.Written() {
+		return apperr.As(c.Errors.Last().Err).Status()
+	}
+	return c.Writer.Status()
+}
+
+// WriteProblem aborts the request with the problem document for err
+func WriteProblem(c *gin.Context, err error) {
+	problem := apperr.Problem(err, c.Request.URL.Path, logging.RequestID(c))
+	c.Header("Content-Type", problemContentType)
+	c.Header("Cache-Control", "no-store")
+	// validators set for the representation the handler meant to send do
+	// not apply to the problem
+	c.Writer.Header().Del("ETag")
+	c.Writer.Header().Del("Last-Modified")
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
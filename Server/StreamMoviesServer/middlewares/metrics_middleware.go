@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/metrics"
+)
+
+// Metrics counts and times requests by route template, so /movie/tt0111161
+// and /movie/tt0068646 share the /movie/:imdb_id series. Requests that match
+// no route are counted under "unmatched".
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		done := metrics.RequestStarted(c.Request.Method)
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		done(route, c.Writer.Status())
+	}
+}
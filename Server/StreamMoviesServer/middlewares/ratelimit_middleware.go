@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/apperr"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/metrics"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/ratelimit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/utils"
+)
+
+// RateLimitHeaders are the response headers set by RateLimit, for CORS to
+// expose
+var RateLimitHeaders = []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"}
+
+// RateLimit applies the named policy of limiter. Policies keyed by user must
+// run after AuthMiddleWare. Requests are let through when the store cannot
+// be reached, so an outage of the store does not take the routes down.
+func RateLimit(limiter *ratelimit.Limiter, name string) gin.HandlerFunc {
+	policy, ok := limiter.Policy(name)
+	if !ok {
+		panic(fmt.Sprintf("unknown rate limit policy %q", name))
+	}
+	return func(c *gin.Context) {
+		if err := CheckRateLimit(c, limiter, policy); err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CheckRateLimit takes a token from policy for the request and sets the
+// RateLimit headers, returning an error when none was left. It is RateLimit
+// for handlers that limit only some of their work, such as a GraphQL
+// mutation. Store errors let the request through.
+func CheckRateLimit(c *gin.Context, limiter *ratelimit.Limiter, policy ratelimit.Policy) error {
+	if !policy.Enabled() {
+		return nil
+	}
+	decision, err := limiter.Allow(c, policy, rateLimitClient(c, policy.Key))
+	if err != nil {
+		metrics.RateLimit(policy.Name, metrics.Error)
+		logger.WarnContext(c, "rate limit check failed", "policy", policy.Name, "error", err)
+		return nil
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(policy.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	c.Header("RateLimit-Reset", wholeSeconds(decision.Reset))
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%s", policy.Limit, wholeSeconds(policy.Period)))
+	if !decision.Allowed {
+		metrics.RateLimit(policy.Name, metrics.RateLimited)
+		c.Header("Retry-After", wholeSeconds(decision.RetryAfter))
+		return apperr.RateLimited(policy.Name)
+	}
+	metrics.RateLimit(policy.Name, metrics.RateAllowed)
+	return nil
+}
+
+// rateLimitClient identifies the client a request is counted against. The
+// user id is the verified one set by AuthMiddleWare, anonymous requests are
+// counted by IP address.
+func rateLimitClient(c *gin.Context, by ratelimit.KeyBy) string {
+	if by == ratelimit.ByUser {
+		if userID, err := utils.GetUserIDFromContext(c); err == nil {
+			return "user:" + userID
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// wholeSeconds rounds up, so clients waiting that long find a token
+func wholeSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/audit"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// the retention TTL index on at is managed by audit.EnsureRetention, these
+// serve the filters of GET /admin/audit
+func auditLogIndexes(ctx context.Context, client *mongo.Client, dryRun bool) (int, error) {
+	if dryRun {
+		return 0, nil
+	}
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "at", Value: -1}}},
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "at", Value: -1}}},
+		{Keys: bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}, {Key: "at", Value: -1}}},
+	}
+	_, err := database.OpenCollection(audit.Collection, client).Indexes().CreateMany(ctx, indexes)
+	return 0, err
+}
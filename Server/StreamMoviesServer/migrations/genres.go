@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func genreIndexes(ctx context.Context, client *mongo.Client, dryRun bool) (int, error) {
+	if dryRun {
+		return 0, nil
+	}
+	index := mongo.IndexModel{Keys: bson.D{{Key: "genre_id", Value: 1}}, Options: options.Index().SetUnique(true)}
+	_, err := database.OpenCollection("genres", client).Indexes().CreateOne(ctx, index)
+	return 0, err
+}
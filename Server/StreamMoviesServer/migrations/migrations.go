@@ -0,0 +1,138 @@
+// Package migrations contains the data migrations applied with "moviestream migrate"
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Migration changes existing documents to match the current models. Up must
+// be safe to run again and returns how many documents it changed, or would
+// change when dryRun is set.
+type Migration struct {
+	ID          string
+	Description string
+	Up          func(ctx context.Context, client *mongo.Client, dryRun bool) (int, error)
+}
+
+// all lists migrations in the order they are applied, append new ones at the end
+var all = []Migration{
+	{
+		ID:          "20261019-movie-metadata",
+		Description: "index people and movie metadata, backfill release_year and link cast and crew to people",
+		Up:          movieMetadata,
+	},
+	{
+		ID:          "20261020-saved-movies",
+		Description: "unique (user_id, imdb_id) indexes on watchlist and favourites",
+		Up:          savedMovieIndexes,
+	},
+	{
+		ID:          "20261020-watch-history",
+		Description: "unique (user_id, imdb_id) and recency indexes on watch_history",
+		Up:          watchHistoryIndexes,
+	},
+	{
+		ID:          "20261021-recommendations",
+		Description: "unique indexes on ratings and recommendations",
+		Up:          recommendationIndexes,
+	},
+	{
+		ID:          "20261022-rankings",
+		Description: "unique ranking_value and case-insensitive unique ranking_name on rankings",
+		Up:          rankingIndexes,
+	},
+	{
+		ID:          "20261023-audit-log",
+		Description: "actor, action and target indexes on audit_log",
+		Up:          auditLogIndexes,
+	},
+	{
+		ID:          "20261024-movie-versions",
+		Description: "start version and updated_at of existing movies, which back their ETags",
+		Up:          movieVersions,
+	},
+	{
+		ID:          "20261025-genres",
+		Description: "unique genre_id on genres",
+		Up:          genreIndexes,
+	},
+}
+
+// indexes are the migrations that only create indexes handlers rely on, such
+// as the unique ones that keep duplicates out. The server applies them on
+// start, so a fresh deployment has them before its first migrate run. The
+// people indexes come from a migration that also backfills movies, so they
+// are created on their own.
+var indexes = map[string]bool{
+	"20261020-saved-movies":    true,
+	"20261020-watch-history":   true,
+	"20261021-recommendations": true,
+	"20261022-rankings":        true,
+	"20261025-genres":          true,
+}
+
+// EnsureIndexes creates the indexes handlers rely on, leaving existing ones
+// as they are. It fails when documents already break a unique index.
+func EnsureIndexes(ctx context.Context, client *mongo.Client) error {
+	if err := movieMetadataIndexes(ctx, client); err != nil {
+		return fmt.Errorf("migration 20261019-movie-metadata: %w", err)
+	}
+	for _, migration := range all {
+		if !indexes[migration.ID] {
+			continue
+		}
+		if _, err := migration.Up(ctx, client, false); err != nil {
+			return fmt.Errorf("migration %s: %w", migration.ID, err)
+		}
+	}
+	return nil
+}
+
+type Result struct {
+	Migration Migration
+	Skipped   bool
+	Changed   int
+}
+
+type appliedMigration struct {
+	ID        string    `bson:"migration_id"`
+	AppliedAt time.Time `bson:"applied_at"`
+	Changed   int       `bson:"changed"`
+}
+
+// Run applies every migration not yet recorded in the migrations collection.
+// With dryRun nothing is written and pending migrations only report counts.
+func Run(ctx context.Context, client *mongo.Client, dryRun bool, progress func(Result)) error {
+	migrationCollection := database.OpenCollection("migrations", client)
+	for _, migration := range all {
+		err := migrationCollection.FindOne(ctx, bson.M{"migration_id": migration.ID}).Err()
+		if err == nil {
+			progress(Result{Migration: migration, Skipped: true})
+			continue
+		}
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		changed, err := migration.Up(ctx, client, dryRun)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", migration.ID, err)
+		}
+		if !dryRun {
+			record := appliedMigration{ID: migration.ID, AppliedAt: time.Now(), Changed: changed}
+			opts := options.UpdateOne().SetUpsert(true)
+			if _, err := migrationCollection.UpdateOne(ctx, bson.M{"migration_id": migration.ID}, bson.M{"$set": record}, opts); err != nil {
+				return fmt.Errorf("recording migration %s: %w", migration.ID, err)
+			}
+		}
+		progress(Result{Migration: migration, Changed: changed})
+	}
+	return nil
+}
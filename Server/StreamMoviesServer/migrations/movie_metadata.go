@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func movieMetadata(ctx context.Context, client *mongo.Client, dryRun bool) (int, error) {
+	if !dryRun {
+		if err := movieMetadataIndexes(ctx, client); err != nil {
+			return 0, err
+		}
+	}
+
+	missingPerson := bson.M{"$elemMatch": bson.M{"person_id": bson.M{"$exists": false}}}
+	filter := bson.M{"$or": bson.A{
+		bson.M{"release_date": bson.M{"$exists": true, "$ne": ""}, "release_year": bson.M{"$exists": false}},
+		bson.M{"cast": missingPerson},
+		bson.M{"crew": missingPerson},
+	}}
+	movieCollection := database.OpenCollection("movies", client)
+	if dryRun {
+		count, err := movieCollection.CountDocuments(ctx, filter)
+		return int(count), err
+	}
+
+	cursor, err := movieCollection.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	changed := 0
+	for cursor.Next(ctx) {
+		var movie models.Movie
+		if err := cursor.Decode(&movie); err != nil {
+			return changed, err
+		}
+		if err := catalogue.PrepareMovie(ctx, client, &movie); err != nil {
+			return changed, err
+		}
+		set := bson.M{"cast": movie.Cast, "crew": movie.Crew}
+		if movie.ReleaseYear != 0 {
+			set["release_year"] = movie.ReleaseYear
+		}
+		if _, err := movieCollection.UpdateOne(ctx, bson.M{"_id": movie.ID}, bson.M{"$set": set}); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, cursor.Err()
+}
+
+func movieMetadataIndexes(ctx context.Context, client *mongo.Client) error {
+	people := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "person_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{
+			Keys: bson.D{{Key: "tmdb_id", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"tmdb_id": bson.M{"$exists": true}}),
+		},
+		{Keys: bson.D{{Key: "name", Value: 1}}},
+	}
+	if _, err := database.OpenCollection("people", client).Indexes().CreateMany(ctx, people); err != nil {
+		return err
+	}
+	movies := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "release_year", Value: 1}}},
+		{Keys: bson.D{{Key: "original_language", Value: 1}}},
+		{Keys: bson.D{{Key: "countries", Value: 1}}},
+		{Keys: bson.D{{Key: "cast.person_id", Value: 1}}},
+		{Keys: bson.D{{Key: "crew.person_id", Value: 1}}},
+	}
+	_, err := database.OpenCollection("movies", client).Indexes().CreateMany(ctx, movies)
+	return err
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// movieVersions starts movies written before versions were kept at version
+// 1, changed now, so they get an ETag and a Last-Modified
+func movieVersions(ctx context.Context, client *mongo.Client, dryRun bool) (int, error) {
+	movieCollection := database.OpenCollection("movies", client)
+	unversioned := bson.M{"version": bson.M{"$exists": false}}
+	if dryRun {
+		count, err := movieCollection.CountDocuments(ctx, unversioned)
+		return int(count), err
+	}
+	result, err := movieCollection.UpdateMany(ctx, unversioned, database.Versioned(bson.M{}))
+	if err != nil {
+		return 0, err
+	}
+	return int(result.ModifiedCount), nil
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func rankingIndexes(ctx context.Context, client *mongo.Client, dryRun bool) (int, error) {
+	if dryRun {
+		return 0, nil
+	}
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "ranking_value", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{
+			Keys:    bson.D{{Key: "ranking_name", Value: 1}},
+			Options: options.Index().SetUnique(true).SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+		},
+	}
+	_, err := database.OpenCollection("rankings", client).Indexes().CreateMany(ctx, indexes)
+	return 0, err
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func recommendationIndexes(ctx context.Context, client *mongo.Client, dryRun bool) (int, error) {
+	if dryRun {
+		return 0, nil
+	}
+	ratings := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "imdb_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "rated_at", Value: -1}}},
+	}
+	if _, err := database.OpenCollection("ratings", client).Indexes().CreateMany(ctx, ratings); err != nil {
+		return 0, err
+	}
+	recommendations := mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)}
+	_, err := database.OpenCollection("recommendations", client).Indexes().CreateOne(ctx, recommendations)
+	return 0, err
+}
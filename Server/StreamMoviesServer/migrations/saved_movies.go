@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func savedMovieIndexes(ctx context.Context, client *mongo.Client, dryRun bool) (int, error) {
+	if dryRun {
+		return 0, nil
+	}
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "imdb_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "added_at", Value: -1}}},
+	}
+	for _, list := range []string{"watchlist", "favourites"} {
+		if _, err := database.OpenCollection(list, client).Indexes().CreateMany(ctx, indexes); err != nil {
+			return 0, err
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// watchHistoryIndexes leaves out the retention TTL index, which follows
+// HISTORY_RETENTION_DAYS and is maintained when the server starts
+func watchHistoryIndexes(ctx context.Context, client *mongo.Client, dryRun bool) (int, error) {
+	if dryRun {
+		return 0, nil
+	}
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "imdb_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_watched_at", Value: -1}}},
+	}
+	_, err := database.OpenCollection("watch_history", client).Indexes().CreateMany(ctx, indexes)
+	return 0, err
+}
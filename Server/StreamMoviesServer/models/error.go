@@ -1,5 +1,49 @@
 package models
 
+import "encoding/json"
+
+// ErrorResponse is an RFC 7807 problem document, served as
+// application/problem+json. Code is stable and meant for clients to switch
+// on, Detail is meant for people.
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Type      string       `json:"type" example:"about:blank"`
+	Title     string       `json:"title" example:"Not Found"`
+	Status    int          `json:"status" example:"404"`
+	Detail    string       `json:"detail,omitempty" example:"Movie not found"`
+	Instance  string       `json:"instance,omitempty" example:"/api/v1/movie/tt0111161"`
+	Code      string       `json:"code" example:"movie_not_found"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	// Extensions are problem specific members, such as the number of movies
+	// still using a genre, written next to the others
+	Extensions map[string]any `json:"-"`
+}
+
+// FieldError is a field of the request that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (r ErrorResponse) MarshalJSON() ([]byte, error) {
+	type plain ErrorResponse
+	encoded, err := json.Marshal(plain(r))
+	if err != nil || len(r.Extensions) == 0 {
+		return encoded, err
+	}
+	members := map[string]any{}
+	for key, value := range r.Extensions {
+		members[key] = value
+	}
+	// standard members win over extensions of the same name
+	var standard map[string]any
+	if err := json.Unmarshal(encoded, &standard); err != nil {
+		return nil, err
+	}
+	for key, value := range standard {
+		members[key] = value
+	}
+	return json.Marshal(members)
 }
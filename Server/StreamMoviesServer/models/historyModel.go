@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// WatchEvent reports how far a user got in a trailer or stream. Sending the
+// same event again leaves the history unchanged and events older than the
+// stored progress are ignored. WatchedAt defaults to the time it is received.
+type WatchEvent struct {
+	ImdbID          string    `json:"imdb_id" validate:"required,imdb_id"`
+	PositionSeconds int       `json:"position_seconds" validate:"min=0"`
+	DurationSeconds int       `json:"duration_seconds" validate:"min=0"`
+	Completed       bool      `json:"completed"`
+	Device          string    `json:"device" validate:"max=100"`
+	WatchedAt       time.Time `json:"watched_at,omitzero"`
+}
+
+// HistoryEntry is the latest progress of a user on one movie. Movie is only
+// filled in when listing, it is never stored.
+type HistoryEntry struct {
+	UserID          string    `bson:"user_id" json:"-"`
+	ImdbID          string    `bson:"imdb_id" json:"imdb_id"`
+	PositionSeconds int       `bson:"position_seconds" json:"position_seconds"`
+	DurationSeconds int       `bson:"duration_seconds" json:"duration_seconds"`
+	Completed       bool      `bson:"completed" json:"completed"`
+	Device          string    `bson:"device,omitempty" json:"device,omitempty"`
+	FirstWatchedAt  time.Time `bson:"first_watched_at" json:"first_watched_at"`
+	LastWatchedAt   time.Time `bson:"last_watched_at" json:"last_watched_at"`
+	Movie           *Movie    `bson:"movie,omitempty" json:"movie,omitempty"`
+}
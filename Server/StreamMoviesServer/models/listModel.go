@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// SavedMovie is a movie a user put on their watchlist or favourites. Movie is
+// only filled in when listing, it is never stored.
+type SavedMovie struct {
+	ID      bson.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID  string        `bson:"user_id" json:"-"`
+	ImdbID  string        `bson:"imdb_id" json:"imdb_id"`
+	AddedAt time.Time     `bson:"added_at" json:"added_at"`
+	Movie   *Movie        `bson:"movie,omitempty" json:"movie,omitempty"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
@@ -10,21 +12,99 @@ type Genre struct {
 }
 
 type Ranking struct {
-	RankingValue int    `bson:"ranking_value" json:"ranking_value" validate:"required"`
-	RankingName  string `bson:"ranking_name" json:"ranking_name" validate:"required"`
+	RankingValue int    `bson:"ranking_value" json:"ranking_value" validate:"required,min=1"`
+	RankingName  string `bson:"ranking_name" json:"ranking_name" validate:"required,max=100"`
+}
+
+type GenreUpdate struct {
+	GenreName string `json:"genre_name" validate:"required,min=2,max=100"`
+}
+
+// GenreWithCount is a genre and how many movies have it
+type GenreWithCount struct {
+	Genre      `bson:",inline"`
+	MovieCount int `bson:"movie_count" json:"movie_count"`
 }
 
 type Movie struct {
-	ID          bson.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-	ImdbID      string        `bson:"imdb_id" json:"imdb_id" validate:"required"`
-	Title       string        `bson:"title" json:"title" validate:"required,min=2,max=500"`
-	PosterPath  string        `bson:"poster_path" json:"poster_path" validate:"required,url"`
-	YouTubeID   string        `bson:"youtube_id" json:"youtube_id" validate:"required"`
-	Genre       []Genre       `bson:"genre" json:"genre" validate:"required,dive"`
-	AdminReview string        `bson:"admin_review" json:"admin_review"`
-	Ranking     Ranking       `bson:"ranking" json:"ranking" validate:"required"`
+	ID               bson.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	ImdbID           string        `bson:"imdb_id" json:"imdb_id" validate:"required,imdb_id"`
+	Title            string        `bson:"title" json:"title" validate:"required,min=2,max=500"`
+	PosterPath       string        `bson:"poster_path" json:"poster_path" validate:"required,poster_url"`
+	YouTubeID        string        `bson:"youtube_id" json:"youtube_id" validate:"required,youtube_id"`
+	Genre            []Genre       `bson:"genre" json:"genre" validate:"required,dive"`
+	AdminReview      string        `bson:"admin_review" json:"admin_review"`
+	Ranking          Ranking       `bson:"ranking" json:"ranking" validate:"required"`
+	TMDBID           int           `bson:"tmdb_id,omitempty" json:"tmdb_id,omitempty"`
+	Overview         string        `bson:"overview,omitempty" json:"overview,omitempty" validate:"omitempty,max=5000"`
+	ReleaseDate      string        `bson:"release_date,omitempty" json:"release_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	ReleaseYear      int           `bson:"release_year,omitempty" json:"release_year,omitempty" validate:"omitempty,min=1870,max=2200"`
+	Runtime          int           `bson:"runtime,omitempty" json:"runtime,omitempty" validate:"omitempty,min=1,max=1500"`
+	OriginalLanguage string        `bson:"original_language,omitempty" json:"original_language,omitempty" validate:"omitempty,len=2,lowercase"`
+	SpokenLanguages  []string      `bson:"spoken_languages,omitempty" json:"spoken_languages,omitempty" validate:"omitempty,dive,len=2,lowercase"`
+	Countries        []string      `bson:"countries,omitempty" json:"countries,omitempty" validate:"omitempty,dive,iso3166_1_alpha2"`
+	AgeCertification string        `bson:"age_certification,omitempty" json:"age_certification,omitempty" validate:"omitempty,max=10"`
+	Cast             []CastMember  `bson:"cast,omitempty" json:"cast,omitempty" validate:"omitempty,dive"`
+	Crew             []CrewMember  `bson:"crew,omitempty" json:"crew,omitempty" validate:"omitempty,dive"`
+	// Version goes up with every change and UpdatedAt is the time of the
+	// last one, both are set by the server and back the movie's ETag
+	Version   int64     `bson:"version,omitempty" json:"version,omitempty"`
+	UpdatedAt time.Time `bson:"updated_at,omitempty" json:"updated_at,omitzero"`
+}
+
+// CastMember and CrewMember keep a copy of the person's name next to the
+// person_id reference so listings do not need a lookup into people
+type CastMember struct {
+	PersonID    string `bson:"person_id,omitempty" json:"person_id,omitempty"`
+	Name        string `bson:"name" json:"name" validate:"required"`
+	Character   string `bson:"character" json:"character"`
+	Order       int    `bson:"order" json:"order"`
+	ProfilePath string `bson:"profile_path,omitempty" json:"profile_path,omitempty"`
+	TMDBID      int    `bson:"tmdb_id,omitempty" json:"tmdb_id,omitempty"`
+}
+
+type CrewMember struct {
+	PersonID    string `bson:"person_id,omitempty" json:"person_id,omitempty"`
+	Name        string `bson:"name" json:"name" validate:"required"`
+	Job         string `bson:"job" json:"job" validate:"required"`
+	Department  string `bson:"department" json:"department"`
+	ProfilePath string `bson:"profile_path,omitempty" json:"profile_path,omitempty"`
+	TMDBID      int    `bson:"tmdb_id,omitempty" json:"tmdb_id,omitempty"`
 }
 
 type UpdateReview struct {
-	AdminReview string `json:"admin_review"`
+	AdminReview string `json:"admin_review" validate:"required,max=5000"`
+}
+
+// SimilarMovie is a movie found by embedding similarity, Similarity runs
+// from -1 to 1
+type SimilarMovie struct {
+	Movie      Movie   `json:"movie"`
+	Similarity float64 `json:"similarity"`
+}
+
+// RecommendedMovie is a movie with the score it was recommended with and the
+// reasons behind it, strongest first
+type RecommendedMovie struct {
+	Movie   `bson:",inline"`
+	Score   float64                `bson:"score" json:"score"`
+	Reasons []RecommendationReason `bson:"reasons" json:"reasons"`
+}
+
+// RecommendationReason explains part of a recommendation. Kind is one of
+// similar_to, favourite_genre, liked_genre and highly_ranked, the other
+// fields name what the reason refers to and Text is ready to show.
+type RecommendationReason struct {
+	Kind         string  `bson:"kind" json:"kind"`
+	Text         string  `bson:"text" json:"text"`
+	Contribution float64 `bson:"contribution" json:"contribution"`
+	ImdbID       string  `bson:"imdb_id,omitempty" json:"imdb_id,omitempty"`
+	Title        string  `bson:"title,omitempty" json:"title,omitempty"`
+	Genre        string  `bson:"genre,omitempty" json:"genre,omitempty"`
+	Ranking      string  `bson:"ranking,omitempty" json:"ranking,omitempty"`
+}
+
+type AskRequest struct {
+	Text  string `json:"text" validate:"required,max=500"`
+	Limit int    `json:"limit" validate:"omitempty,min=1,max=50"`
 }
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type Person struct {
+	ID                 bson.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	PersonID           string        `bson:"person_id" json:"person_id"`
+	Name               string        `bson:"name" json:"name" validate:"required,min=1,max=200"`
+	Biography          string        `bson:"biography,omitempty" json:"biography,omitempty" validate:"omitempty,max=10000"`
+	Birthday           string        `bson:"birthday,omitempty" json:"birthday,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	PlaceOfBirth       string        `bson:"place_of_birth,omitempty" json:"place_of_birth,omitempty" validate:"omitempty,max=200"`
+	KnownForDepartment string        `bson:"known_for_department,omitempty" json:"known_for_department,omitempty" validate:"omitempty,max=100"`
+	ProfilePath        string        `bson:"profile_path,omitempty" json:"profile_path,omitempty"`
+	TMDBID             int           `bson:"tmdb_id,omitempty" json:"tmdb_id,omitempty"`
+	CreatedAt          time.Time     `bson:"created_at,omitzero" json:"created_at,omitzero"`
+	UpdatedAt          time.Time     `bson:"update_at,omitzero" json:"update_at,omitzero"`
+}
+
+// FilmographyCredit is one credit of a person: a cast role with its
+// character and billing order, or a crew job
+type FilmographyCredit struct {
+	ImdbID      string  `json:"imdb_id"`
+	Title       string  `json:"title"`
+	PosterPath  string  `json:"poster_path"`
+	ReleaseYear int     `json:"release_year,omitempty"`
+	Ranking     Ranking `json:"ranking"`
+	Role        string  `json:"role"`
+	Character   string  `json:"character,omitempty"`
+	Order       int     `json:"order"`
+	Job         string  `json:"job,omitempty"`
+	Department  string  `json:"department,omitempty"`
+}
+
+type PersonDetails struct {
+	Person
+	Filmography []FilmographyCredit `json:"filmography"`
+}
+
+// Page is a single page of a paginated listing
+type Page[T any] struct {
+	Items    []T   `json:"items"`
+	Page     int64 `json:"page"`
+	PageSize int64 `json:"page_size"`
+	Total    int64 `json:"total"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Rating is a user's own score for a movie, from 1 (hated it) to 5 (loved it)
+type Rating struct {
+	UserID  string    `bson:"user_id" json:"-"`
+	ImdbID  string    `bson:"imdb_id" json:"imdb_id"`
+	Rating  int       `bson:"rating" json:"rating" validate:"required,min=1,max=5"`
+	RatedAt time.Time `bson:"rated_at" json:"rated_at"`
+}
@@ -13,8 +13,8 @@ type User struct {
 	FirstName            string        `json:"first_name" bson:"first_name" validate:"required,min=2,max=100"`
 	LastName             string        `json:"last_name" bson:"last_name" validate:"required,min=2,max=100"`
 	Email                string        `json:"email" bson:"email" validate:"required,email"`
-	Password             string        `json:"password,omitempty" bson:"password,omitempty" validate:"omitempty,min=6"`
-	Role                 string        `json:"role" bson:"role" validate:"oneof=ADMIN USER"`
+	Password             string        `json:"password,omitempty" bson:"password,omitempty" validate:"required,password"`
+	Role                 string        `json:"role" bson:"role" validate:"omitempty,oneof=ADMIN USER"`
 	CreatedAt            time.Time     `json:"created_at" bson:"created_at"`
 	UpdatedAt            time.Time     `json:"update_at" bson:"update_at"`
 	Token                string        `json:"token" bson:"token"`
@@ -45,6 +45,9 @@ type MailData struct {
 	Subject  string
 	Content  string
 	Template string
+	// TraceContext carries the trace of the request that queued the mail
+	// to the goroutine sending it
+	TraceContext map[string]string
 }
 
 type UpdateUser struct {
@@ -57,7 +60,7 @@ type UpdateUser struct {
 }
 
 type LogoutRequest struct {
-	UserID string `json:"user_id"`
+	UserID string `json:"user_id" validate:"required"`
 }
 
 type PasswordResetRequest struct {
@@ -66,5 +69,9 @@ type PasswordResetRequest struct {
 
 type PasswordReset struct {
 	Token       string `json:"token" validate:"required"`
-	NewPassword string `json:"new_password" validate:"required,min=6"`
+	NewPassword string `json:"new_password" validate:"required,password"`
+}
+
+type RoleUpdate struct {
+	Role string `json:"role" validate:"required,oneof=ADMIN USER"`
 }
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is the number of takes between sweeps of idle buckets
+const sweepEvery = 1000
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	expires time.Time
+}
+
+// MemoryStore keeps buckets in the process, so every instance limits on its
+// own
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	takes   int
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*memoryBucket{}, now: time.Now}
+}
+
+func (s *MemoryStore) Take(_ context.Context, id string, policy Policy) (Bucket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.takes++
+	if s.takes%sweepEvery == 0 {
+		for key, bucket := range s.buckets {
+			if now.After(bucket.expires) {
+				delete(s.buckets, key)
+			}
+		}
+	}
+
+	bucket, ok := s.buckets[id]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(policy.Limit), updated: now}
+		s.buckets[id] = bucket
+	}
+	bucket.tokens = refill(bucket.tokens, now.Sub(bucket.updated), policy)
+	bucket.updated = now
+	bucket.expires = now.Add(policy.Period)
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	return Bucket{Tokens: bucket.tokens, Allowed: allowed}, nil
+}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterWithMemoryStore(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	// bursts of 2, a token back every second
+	policy := Policy{Name: "test", Limit: 2, Period: 2 * time.Second, Key: ByIP}
+	limiter := NewLimiter(store, map[string]Policy{policy.Name: policy})
+
+	steps := []struct {
+		name       string
+		advance    time.Duration
+		allowed    bool
+		remaining  int
+		reset      time.Duration
+		retryAfter time.Duration
+	}{
+		{"first", 0, true, 1, time.Second, 0},
+		{"second", 0, true, 0, 2 * time.Second, 0},
+		{"burst used up", 0, false, 0, 2 * time.Second, time.Second},
+		{"half a token back", 500 * time.Millisecond, false, 0, 1500 * time.Millisecond, 500 * time.Millisecond},
+		{"a whole token back", 500 * time.Millisecond, true, 0, 2 * time.Second, 0},
+		{"refilled no further than the limit", time.Hour, true, 1, time.Second, 0},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		decision, err := limiter.Allow(context.Background(), policy, "ip:192.0.2.1")
+		if err != nil {
+			t.Fatalf("%s: Allow: %v", step.name, err)
+		}
+		if decision.Allowed != step.allowed || decision.Remaining != step.remaining ||
+			decision.Reset != step.reset || decision.RetryAfter != step.retryAfter {
+			t.Errorf("%s: decision = %+v, want allowed %v, remaining %d, reset %v, retry after %v",
+				step.name, decision, step.allowed, step.remaining, step.reset, step.retryAfter)
+		}
+	}
+
+	// other clients have buckets of their own
+	decision, err := limiter.Allow(context.Background(), policy, "ip:192.0.2.2")
+	if err != nil || !decision.Allowed || decision.Remaining != 1 {
+		t.Errorf("other client: decision = %+v, err = %v", decision, err)
+	}
+}
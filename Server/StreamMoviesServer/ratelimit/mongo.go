@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	Collection  = "rate_limits"
+	expiryIndex = "rate_limits_expiry"
+)
+
+// MongoStore keeps buckets in MongoDB, shared by every instance. Each take is
+// a single pipeline update timed by the server clock, so instances need not
+// agree on the time and concurrent takes cannot both spend the last token.
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStore also makes MongoDB drop buckets once they are idle for a
+// full period
+func NewMongoStore(ctx context.Context, client *mongo.Client) (*MongoStore, error) {
+	collection := database.OpenCollection(Collection, client)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetName(expiryIndex).SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MongoStore{collection: collection}, nil
+}
+
+func (s *MongoStore) Take(ctx context.Context, id string, policy Policy) (Bucket, error) {
+	limit := float64(policy.Limit)
+	elapsed := bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{"$$NOW", bson.M{"$ifNull": bson.A{"$updated_at", "$$NOW"}}}},
+		1000,
+	}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{limit, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", limit}},
+				bson.M{"$multiply": bson.A{elapsed, policy.rate()}},
+			}}}},
+			"updated_at": "$$NOW",
+		}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens":     bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"expires_at": bson.M{"$add": bson.A{"$$NOW", policy.Period.Milliseconds()}},
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var bucket struct {
+		Tokens  float64 `bson:"tokens"`
+		Allowed bool    `bson:"allowed"`
+	}
+	if err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, pipeline, opts).Decode(&bucket); err != nil {
+		return Bucket{}, err
+	}
+	return Bucket{Tokens: bucket.Tokens, Allowed: bucket.Allowed}, nil
+}
@@ -0,0 +1,196 @@
+// Package ratelimit throttles requests with token buckets. A policy holds up
+// to Limit tokens, refilled evenly over Period, and every request takes one.
+// Buckets are kept per policy and client, where the client is an IP address
+// or a user id, in a Store: in memory for a single instance or in
+// MongoDB so limits hold across instances.
+//
+// Clients are deliberately not keyed by API key: the server issues and
+// verifies none, and a key taken from a request header as sent would let
+// every caller pick a fresh bucket.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// KeyBy is what identifies the client a bucket belongs to
+type KeyBy string
+
+const (
+	ByIP KeyBy = "ip"
+	// ByUser falls back to the IP address for anonymous requests
+	ByUser KeyBy = "user"
+	// there is no API key mode, see the package documentation
+)
+
+// Policy allows bursts of Limit requests and Limit requests per Period on
+// average. A zero Limit turns the policy off.
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+	Key    KeyBy
+}
+
+// Enabled reports whether the policy limits anything
+func (p Policy) Enabled() bool {
+	return p.Limit > 0 && p.Period > 0
+}
+
+// rate is the number of tokens added back per second
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// defaultPolicies guard the routes that send mail, check passwords or call
+// the language model
+var defaultPolicies = []Policy{
+	{Name: "login", Limit: 10, Period: time.Minute, Key: ByIP},
+	{Name: "register", Limit: 5, Period: time.Hour, Key: ByIP},
+	{Name: "reset", Limit: 3, Period: time.Hour, Key: ByIP},
+	{Name: "review", Limit: 20, Period: time.Hour, Key: ByUser},
+	{Name: "ask", Limit: 30, Period: time.Hour, Key: ByUser},
+}
+
+// PoliciesFromEnv is the default policies with the comma separated RATE_LIMITS
+// applied. Each entry is name=limit/period, optionally followed by :key, such
+// as "login=5/1m:ip" or "review=0/1h" to turn review limits off.
+func PoliciesFromEnv() (map[string]Policy, error) {
+	policies := map[string]Policy{}
+	for _, policy := range defaultPolicies {
+		policies[policy.Name] = policy
+	}
+	for _, entry := range strings.Split(os.Getenv("RATE_LIMITS"), ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		policy, err := parsePolicy(entry, policies)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RATE_LIMITS entry %q: %w", entry, err)
+		}
+		policies[policy.Name] = policy
+	}
+	return policies, nil
+}
+
+func parsePolicy(entry string, policies map[string]Policy) (Policy, error) {
+	name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+	if !ok || name == "" {
+		return Policy{}, fmt.Errorf("expected name=limit/period")
+	}
+	policy, known := policies[name]
+	if !known {
+		policy = Policy{Name: name, Key: ByIP}
+	}
+	value, key, hasKey := strings.Cut(value, ":")
+	if hasKey {
+		switch KeyBy(key) {
+		case ByIP, ByUser:
+			policy.Key = KeyBy(key)
+		default:
+			return Policy{}, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	limit, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("expected name=limit/period")
+	}
+	var err error
+	if policy.Limit, err = strconv.Atoi(limit); err != nil || policy.Limit < 0 {
+		return Policy{}, fmt.Errorf("invalid limit %q", limit)
+	}
+	if policy.Period, err = time.ParseDuration(period); err != nil || policy.Period <= 0 {
+		return Policy{}, fmt.Errorf("invalid period %q", period)
+	}
+	return policy, nil
+}
+
+// Bucket is the state of a bucket after a request tried to take a token
+type Bucket struct {
+	Tokens  float64
+	Allowed bool
+}
+
+// Store takes tokens from buckets. Take refills the bucket for the time
+// passed since it was last used, then takes a token if a whole one is left.
+// Buckets nobody used for a full period may be forgotten.
+type Store interface {
+	Take(ctx context.Context, id string, policy Policy) (Bucket, error)
+}
+
+// StoreFromEnv picks the store named by RATE_LIMIT_STORE: memory (the
+// default) or mongodb
+func StoreFromEnv(ctx context.Context, client *mongo.Client) (Store, error) {
+	switch name := os.Getenv("RATE_LIMIT_STORE"); name {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "mongodb":
+		return NewMongoStore(ctx, client)
+	default:
+		return nil, fmt.Errorf("invalid RATE_LIMIT_STORE %q", name)
+	}
+}
+
+// Decision is the answer to one request. Reset is the time until the bucket
+// is full again and RetryAfter, for refused requests, until it holds a token.
+type Decision struct {
+	Policy     Policy
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type Limiter struct {
+	store    Store
+	policies map[string]Policy
+}
+
+func NewLimiter(store Store, policies map[string]Policy) *Limiter {
+	return &Limiter{store: store, policies: policies}
+}
+
+// Policy returns the policy called name
+func (l *Limiter) Policy(name string) (Policy, bool) {
+	policy, ok := l.policies[name]
+	return policy, ok
+}
+
+// Allow takes a token for client from the policy's bucket. Clients are
+// hashed so stores never hold addresses or keys.
+func (l *Limiter) Allow(ctx context.Context, policy Policy, client string) (Decision, error) {
+	sum := sha256.Sum256([]byte(client))
+	bucket, err := l.store.Take(ctx, policy.Name+":"+hex.EncodeToString(sum[:]), policy)
+	if err != nil {
+		return Decision{Policy: policy, Allowed: true}, err
+	}
+	decision := Decision{
+		Policy:    policy,
+		Allowed:   bucket.Allowed,
+		Remaining: int(math.Floor(bucket.Tokens)),
+		Reset:     seconds((float64(policy.Limit) - bucket.Tokens) / policy.rate()),
+	}
+	if !bucket.Allowed {
+		decision.RetryAfter = seconds((1 - bucket.Tokens) / policy.rate())
+	}
+	return decision, nil
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(math.Max(value, 0) * float64(time.Second))
+}
+
+// refill is tokens after elapsed time, capped at the policy's limit
+func refill(tokens float64, elapsed time.Duration, policy Policy) float64 {
+	return math.Min(float64(policy.Limit), tokens+elapsed.Seconds()*policy.rate())
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePolicyErrors(t *testing.T) {
+	for _, entry := range []string{
+		"login",
+		"=5/1m",
+		"login=5",
+		"login=five/1m",
+		"login=-1/1m",
+		"login=5/soon",
+		"login=5/0s",
+		"login=5/1m:api_key",
+	} {
+		if _, err := parsePolicy(entry, map[string]Policy{}); err == nil {
+			t.Errorf("parsePolicy(%q) succeeded", entry)
+		}
+	}
+}
+
+func TestPoliciesFromEnv(t *testing.T) {
+	t.Setenv("RATE_LIMITS", "login=5/30s:user, review=0/1h,custom=3/1s")
+	policies, err := PoliciesFromEnv()
+	if err != nil {
+		t.Fatalf("PoliciesFromEnv: %v", err)
+	}
+	tests := []struct {
+		name    string
+		want    Policy
+		enabled bool
+	}{
+		{"login", Policy{Name: "login", Limit: 5, Period: 30 * time.Second, Key: ByUser}, true},
+		// turned off, keeping the key of the default
+		{"review", Policy{Name: "review", Limit: 0, Period: time.Hour, Key: ByUser}, false},
+		// new policies are keyed by IP unless told otherwise
+		{"custom", Policy{Name: "custom", Limit: 3, Period: time.Second, Key: ByIP}, true},
+		{"ask", Policy{Name: "ask", Limit: 30, Period: time.Hour, Key: ByUser}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policies[tt.name]
+			if got != tt.want {
+				t.Errorf("policy = %+v, want %+v", got, tt.want)
+			}
+			if got.Enabled() != tt.enabled {
+				t.Errorf("Enabled() = %v, want %v", got.Enabled(), tt.enabled)
+			}
+		})
+	}
+}
+
+func TestRefill(t *testing.T) {
+	policy := Policy{Limit: 10, Period: 10 * time.Second}
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{"nothing elapsed", 3, 0, 3},
+		{"a token per second", 3, 2500 * time.Millisecond, 5.5},
+		{"capped at the limit", 3, time.Hour, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := refill(tt.tokens, tt.elapsed, policy); got != tt.want {
+				t.Errorf("refill = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
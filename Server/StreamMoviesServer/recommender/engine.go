@@ -0,0 +1,208 @@
+// Package recommender builds per-user movie recommendations from what users
+// saved, rated and watched, blended with their genre preferences and the
+// admin ranking, and stores them in the recommendations collection
+package recommender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/logging"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	Collection = "recommendations"
+
+	defaultInterval = time.Hour
+	writeBatchSize  = 500
+)
+
+var logger = logging.For("recommender")
+
+var ErrNoRecommendations = errors.New("no recommendations computed for user")
+
+// Config controls how scores are blended. The three weights should add up
+// to 1, Diversity is the share of its score a candidate loses when its genres
+// match an item already picked (0 turns diversification off).
+type Config struct {
+	CollaborativeWeight float64 `json:"collaborative_weight"`
+	GenreWeight         float64 `json:"genre_weight"`
+	RankingWeight       float64 `json:"ranking_weight"`
+	Diversity           float64 `json:"diversity"`
+	PerUser             int     `json:"per_user"`
+	Neighbours          int     `json:"neighbours"`
+	MaxItemsPerUser     int     `json:"max_items_per_user"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		CollaborativeWeight: 0.6,
+		GenreWeight:         0.25,
+		RankingWeight:       0.15,
+		Diversity:           0.3,
+		PerUser:             50,
+		Neighbours:          50,
+		MaxItemsPerUser:     200,
+	}
+}
+
+// ConfigFromEnv is DefaultConfig with RECOMMENDER_DIVERSITY and
+// RECOMMENDATIONS_PER_USER applied
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	if value, err := strconv.ParseFloat(os.Getenv("RECOMMENDER_DIVERSITY"), 64); err == nil && value >= 0 && value <= 1 {
+		cfg.Diversity = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("RECOMMENDATIONS_PER_USER")); err == nil && value > 0 {
+		cfg.PerUser = value
+	}
+	return cfg
+}
+
+// IntervalFromEnv reads RECOMMENDER_INTERVAL as a duration such as "30m",
+// 0 disables the background job
+func IntervalFromEnv() (time.Duration, error) {
+	value := os.Getenv("RECOMMENDER_INTERVAL")
+	if value == "" {
+		return defaultInterval, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid RECOMMENDER_INTERVAL %q", value)
+	}
+	return interval, nil
+}
+
+// Recommendations is the stored list for one user, best first
+type Recommendations struct {
+	UserID      string    `bson:"user_id" json:"user_id"`
+	Items       []Item    `bson:"items" json:"items"`
+	GeneratedAt time.Time `bson:"generated_at" json:"generated_at"`
+}
+
+type Engine struct {
+	Client *mongo.Client
+	Config Config
+	// Rebuilt, when set, is called by Start after every successful rebuild,
+	// such as to drop cached recommendations
+	Rebuilt func(ctx context.Context)
+}
+
+func NewEngine(client *mongo.Client) *Engine {
+	return &Engine{Client: client, Config: ConfigFromEnv()}
+}
+
+// Rebuild recomputes the recommendations of every user and returns how many
+// users were written
+func (e *Engine) Rebuild(ctx context.Context) (int, error) {
+	c, err := loadCatalogue(ctx, e.Client)
+	if err != nil {
+		return 0, err
+	}
+	neighbours := itemSimilarities(c.profiles, e.Config.Neighbours, e.Config.MaxItemsPerUser)
+
+	now := time.Now()
+	recommendationCollection := database.OpenCollection(Collection, e.Client)
+	writes := make([]mongo.WriteModel, 0, writeBatchSize)
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		_, err := recommendationCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		writes = writes[:0]
+		return err
+	}
+
+	written := 0
+	for userID, p := range c.profiles {
+		recommendations := Recommendations{
+			UserID:      userID,
+			Items:       recommend(e.Config, c, p, neighbours),
+			GeneratedAt: now,
+		}
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"user_id": userID}).
+			SetReplacement(recommendations).
+			SetUpsert(true))
+		if len(writes) == writeBatchSize {
+			if err := flush(); err != nil {
+				return written, err
+			}
+		}
+		written++
+	}
+	if err := flush(); err != nil {
+		return written, err
+	}
+	return written, nil
+}
+
+// Explanation lists every movie a rebuild scores for a user, best first,
+// next to the items it would store after diversification
+type Explanation struct {
+	UserID     string `json:"user_id"`
+	Config     Config `json:"config"`
+	Items      []Item `json:"items"`
+	Candidates []Item `json:"candidates"`
+}
+
+// Explain scores one user from the current data the way Rebuild does, with
+// reasons on every candidate, without storing anything
+func (e *Engine) Explain(ctx context.Context, userID string) (*Explanation, error) {
+	c, err := loadCatalogue(ctx, e.Client)
+	if err != nil {
+		return nil, err
+	}
+	neighbours := itemSimilarities(c.profiles, e.Config.Neighbours, e.Config.MaxItemsPerUser)
+	p := c.profile(userID)
+
+	candidates, evidences := score(e.Config, c, p, neighbours)
+	for i := range candidates {
+		candidates[i].Reasons = reasons(e.Config, c, p, candidates[i], evidences[candidates[i].ImdbID])
+	}
+	items := diversify(e.Config, c, candidates)
+	return &Explanation{UserID: userID, Config: e.Config, Items: items, Candidates: candidates}, nil
+}
+
+// Start rebuilds now and then every interval until ctx is done
+func (e *Engine) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		started := time.Now()
+		if users, err := e.Rebuild(ctx); err != nil {
+			logger.ErrorContext(ctx, "recommendation rebuild failed", "error", err)
+		} else {
+			logger.InfoContext(ctx, "recommendations rebuilt", "users", users, "duration", time.Since(started).Round(time.Millisecond))
+			if e.Rebuilt != nil {
+				e.Rebuilt(ctx)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Load returns the stored recommendations of a user
+func Load(ctx context.Context, client *mongo.Client, userID string) (*Recommendations, error) {
+	var recommendations Recommendations
+	err := database.OpenCollection(Collection, client).FindOne(ctx, bson.M{"user_id": userID}).Decode(&recommendations)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNoRecommendations
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &recommendations, nil
+}
@@ -0,0 +1,99 @@
+package recommender
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+)
+
+var interactionText = map[string]string{
+	favourited:  "added to your favourites",
+	completed:   "watched",
+	watchlisted: "saved to your watchlist",
+	started:     "started watching",
+	rated:       "rated highly",
+}
+
+// reasons explains an item from the evidence of its score, strongest
+// contribution first. Parts that added nothing give no reason.
+func reasons(cfg Config, c *catalogue, p *profile, item Item, e evidence) []models.RecommendationReason {
+	var result []models.RecommendationReason
+	if item.Collaborative > 0 && e.similarTo != "" {
+		source := c.movies[e.similarTo]
+		result = append(result, models.RecommendationReason{
+			Kind:         "similar_to",
+			Text:         fmt.Sprintf("Similar to %s, which you %s", source.title, interactionText[p.strongest[e.similarTo].kind]),
+			Contribution: cfg.CollaborativeWeight * item.Collaborative,
+			ImdbID:       e.similarTo,
+			Title:        source.title,
+		})
+	}
+	if item.Genre > 0 && e.genre != "" {
+		result = append(result, genreReason(e.genre, slices.Contains(p.favouriteGenres, e.genre), cfg.GenreWeight*item.Genre))
+	}
+	movie := c.movies[item.ImdbID]
+	if reason, ok := rankingReason(movie.ranking, movie.rankingName, c.highlyRanked, cfg.RankingWeight*item.Ranking); ok {
+		result = append(result, reason)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Contribution > result[j].Contribution
+	})
+	return result
+}
+
+func genreReason(genre string, favourite bool, contribution float64) models.RecommendationReason {
+	if favourite {
+		return models.RecommendationReason{
+			Kind:         "favourite_genre",
+			Text:         "Matches your favourite genre " + genre,
+			Contribution: contribution,
+			Genre:        genre,
+		}
+	}
+	return models.RecommendationReason{
+		Kind:         "liked_genre",
+		Text:         fmt.Sprintf("%s, like movies you enjoyed", genre),
+		Contribution: contribution,
+		Genre:        genre,
+	}
+}
+
+// rankingReason points out rankings up to highlyRanked, see
+// catalogue.HighlyRanked
+func rankingReason(value int, name string, highlyRanked int, contribution float64) (models.RecommendationReason, bool) {
+	if value < 1 || value > highlyRanked {
+		return models.RecommendationReason{}, false
+	}
+	return models.RecommendationReason{
+		Kind:         "highly_ranked",
+		Text:         fmt.Sprintf("Ranked %s by our admins", name),
+		Contribution: contribution,
+		Ranking:      name,
+	}, true
+}
+
+// ExplainMovie scores and explains a movie for a user the recommender has not
+// run for yet, from their favourite genres and the genres of movies they
+// liked. It blends the same way a rebuild does, with no collaborative part.
+// highlyRanked is the worst ranking value worth pointing out.
+func ExplainMovie(cfg Config, highlyRanked int, movie models.Movie, favouriteGenres, likedGenres []string) (float64, []models.RecommendationReason) {
+	var result []models.RecommendationReason
+	genre, ranking := 0.0, rankingScore(movie.Ranking.RankingValue)
+	// a favourite genre is the better reason when the movie has both
+	for _, genres := range [][]string{favouriteGenres, likedGenres} {
+		index := slices.IndexFunc(movie.Genre, func(g models.Genre) bool {
+			return slices.Contains(genres, g.GenreName)
+		})
+		if index >= 0 {
+			genre = 1
+			result = append(result, genreReason(movie.Genre[index].GenreName, slices.Contains(favouriteGenres, movie.Genre[index].GenreName), cfg.GenreWeight))
+			break
+		}
+	}
+	if reason, ok := rankingReason(movie.Ranking.RankingValue, movie.Ranking.RankingName, highlyRanked, cfg.RankingWeight*ranking); ok {
+		result = append(result, reason)
+	}
+	return cfg.GenreWeight*genre + cfg.RankingWeight*ranking, result
+}
@@ -0,0 +1,204 @@
+package recommender
+
+import (
+	"sort"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+)
+
+// Item is one recommended movie with the parts its score is blended from,
+// each between 0 and 1, and the reasons for it
+type Item struct {
+	ImdbID        string                        `bson:"imdb_id" json:"imdb_id"`
+	Title         string                        `bson:"title,omitempty" json:"title,omitempty"`
+	Score         float64                       `bson:"score" json:"score"`
+	Collaborative float64                       `bson:"collaborative" json:"collaborative"`
+	Genre         float64                       `bson:"genre" json:"genre"`
+	Ranking       float64                       `bson:"ranking" json:"ranking"`
+	Reasons       []models.RecommendationReason `bson:"reasons,omitempty" json:"reasons,omitempty"`
+}
+
+// evidence is what a candidate's score came from: the seen movie adding the
+// most to its collaborative part and the genre behind its genre part
+type evidence struct {
+	similarTo string
+	genre     string
+}
+
+// recommend scores every movie the user has not seen and returns the best
+// cfg.PerUser of them, re-ranked for genre diversity, with their reasons
+func recommend(cfg Config, c *catalogue, p *profile, neighbours map[string][]neighbour) []Item {
+	candidates, evidences := score(cfg, c, p, neighbours)
+	picked := diversify(cfg, c, candidates)
+	for i := range picked {
+		picked[i].Reasons = reasons(cfg, c, p, picked[i], evidences[picked[i].ImdbID])
+	}
+	return picked
+}
+
+// score blends the parts of every movie the user has not seen, best first
+func score(cfg Config, c *catalogue, p *profile, neighbours map[string][]neighbour) ([]Item, map[string]evidence) {
+	collaborative := map[string]float64{}
+	evidences := map[string]evidence{}
+	strongest := map[string]float64{}
+	for imdbID, weight := range p.weights {
+		for _, n := range neighbours[imdbID] {
+			if p.seen[n.imdbID] {
+				continue
+			}
+			contribution := weight * n.similarity
+			collaborative[n.imdbID] += contribution
+			if contribution > strongest[n.imdbID] {
+				strongest[n.imdbID] = contribution
+				evidences[n.imdbID] = evidence{similarTo: imdbID}
+			}
+		}
+	}
+	normalise(collaborative)
+	genres := genrePreferences(c, p)
+
+	candidates := make([]Item, 0, len(c.movies))
+	for imdbID, movie := range c.movies {
+		if p.seen[imdbID] {
+			continue
+		}
+		genreValue, genre := genreScore(genres, movie.genres)
+		item := Item{
+			ImdbID:        imdbID,
+			Title:         movie.title,
+			Collaborative: collaborative[imdbID],
+			Genre:         genreValue,
+			Ranking:       rankingScore(movie.ranking),
+		}
+		item.Score = cfg.CollaborativeWeight*item.Collaborative + cfg.GenreWeight*item.Genre + cfg.RankingWeight*item.Ranking
+		candidates = append(candidates, item)
+		if genre != "" {
+			e := evidences[imdbID]
+			e.genre = genre
+			evidences[imdbID] = e
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].ImdbID < candidates[j].ImdbID
+	})
+	return candidates, evidences
+}
+
+// genrePreferences weighs genres by the user's favourite genres and the
+// genres of the movies they liked, scaled so the strongest is 1
+func genrePreferences(c *catalogue, p *profile) map[string]float64 {
+	preferences := map[string]float64{}
+	for _, genre := range p.favouriteGenres {
+		preferences[genre] += favouriteWeight
+	}
+	for imdbID, weight := range p.weights {
+		if weight <= 0 {
+			continue
+		}
+		for _, genre := range c.movies[imdbID].genres {
+			preferences[genre] += weight
+		}
+	}
+	normalise(preferences)
+	return preferences
+}
+
+// genreScore is the preference for the movie's best matching genre, and
+// that genre
+func genreScore(preferences map[string]float64, genres []string) (float64, string) {
+	best, bestGenre := 0.0, ""
+	for _, genre := range genres {
+		if preferences[genre] > best {
+			best, bestGenre = preferences[genre], genre
+		}
+	}
+	return best, bestGenre
+}
+
+// rankingScore turns the admin ranking, where 1 is best, into 1, 1/2, 1/3...
+// Unranked movies carry a reserved high value and score close to nothing.
+func rankingScore(value int) float64 {
+	if value <= 0 {
+		return 0
+	}
+	return 1 / float64(value)
+}
+
+// diversify picks items greedily, scaling each candidate's score down by how
+// much its genres overlap the most similar item already picked. Only the top
+// few times PerUser candidates are considered to keep the pass cheap.
+func diversify(cfg Config, c *catalogue, candidates []Item) []Item {
+	pool := candidates
+	if limit := cfg.PerUser * 3; len(pool) > limit {
+		pool = pool[:limit]
+	}
+	if cfg.Diversity <= 0 {
+		return pool[:min(cfg.PerUser, len(pool))]
+	}
+
+	picked := make([]Item, 0, min(cfg.PerUser, len(pool)))
+	used := make([]bool, len(pool))
+	for len(picked) < cap(picked) {
+		best, bestScore := -1, 0.0
+		for i, candidate := range pool {
+			if used[i] {
+				continue
+			}
+			overlap := 0.0
+			for _, item := range picked {
+				overlap = max(overlap, jaccard(c.movies[candidate.ImdbID].genres, c.movies[item.ImdbID].genres))
+			}
+			score := candidate.Score * (1 - cfg.Diversity*overlap)
+			if best == -1 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		used[best] = true
+		picked = append(picked, pool[best])
+	}
+	return picked
+}
+
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inA := make(map[string]bool, len(a))
+	for _, genre := range a {
+		inA[genre] = true
+	}
+	shared := 0
+	union := len(inA)
+	counted := map[string]bool{}
+	for _, genre := range b {
+		if counted[genre] {
+			continue
+		}
+		counted[genre] = true
+		if inA[genre] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	return float64(shared) / float64(union)
+}
+
+// normalise scales the values so the largest is 1 and drops anything not
+// positive to 0
+func normalise(values map[string]float64) {
+	highest := 0.0
+	for _, value := range values {
+		highest = max(highest, value)
+	}
+	for key, value := range values {
+		if value <= 0 || highest == 0 {
+			values[key] = 0
+			continue
+		}
+		values[key] = value / highest
+	}
+}
@@ -0,0 +1,216 @@
+package recommender
+
+import (
+	"context"
+	"fmt"
+
+	// the package shares its name with the catalogue type below
+	moviecatalogue "github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// how much each kind of interaction says about a user's taste, ratings add
+// (rating-3)*ratingWeight so a 1 or 2 counts against a movie
+const (
+	favouriteWeight = 3.0
+	completedWeight = 2.0
+	watchlistWeight = 1.0
+	startedWeight   = 0.5
+	ratingWeight    = 1.5
+)
+
+// what a user did with a movie, used to word reasons
+const (
+	favourited  = "favourited"
+	completed   = "completed"
+	watchlisted = "watchlisted"
+	started     = "started"
+	rated       = "rated"
+)
+
+// profile holds the interactions of one user: a taste weight per movie,
+// every movie they already know, the genres they picked as favourites and
+// for each movie the interaction that said the most in its favour
+type profile struct {
+	weights         map[string]float64
+	seen            map[string]bool
+	favouriteGenres []string
+	strongest       map[string]interaction
+}
+
+type interaction struct {
+	kind   string
+	weight float64
+}
+
+type movieInfo struct {
+	title       string
+	genres      []string
+	ranking     int
+	rankingName string
+}
+
+// catalogue is everything a rebuild needs, read once up front
+type catalogue struct {
+	movies   map[string]movieInfo
+	profiles map[string]*profile
+	// highlyRanked is the worst ranking value reasons point out
+	highlyRanked int
+}
+
+func (c *catalogue) profile(userID string) *profile {
+	p, ok := c.profiles[userID]
+	if !ok {
+		p = &profile{weights: map[string]float64{}, seen: map[string]bool{}, strongest: map[string]interaction{}}
+		c.profiles[userID] = p
+	}
+	return p
+}
+
+func (c *catalogue) add(userID, imdbID, kind string, weight float64) {
+	if _, ok := c.movies[imdbID]; !ok {
+		return
+	}
+	p := c.profile(userID)
+	p.weights[imdbID] += weight
+	p.seen[imdbID] = true
+	if weight > p.strongest[imdbID].weight {
+		p.strongest[imdbID] = interaction{kind: kind, weight: weight}
+	}
+}
+
+func loadCatalogue(ctx context.Context, client *mongo.Client) (*catalogue, error) {
+	c := &catalogue{movies: map[string]movieInfo{}, profiles: map[string]*profile{}}
+
+	scale, err := moviecatalogue.LoadRankings(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	c.highlyRanked = moviecatalogue.HighlyRanked(scale)
+
+	var movies []struct {
+		ImdbID string `bson:"imdb_id"`
+		Title  string `bson:"title"`
+		Genre  []struct {
+			GenreName string `bson:"genre_name"`
+		} `bson:"genre"`
+		Ranking struct {
+			RankingValue int    `bson:"ranking_value"`
+			RankingName  string `bson:"ranking_name"`
+		} `bson:"ranking"`
+	}
+	projection := bson.M{"imdb_id": 1, "title": 1, "genre.genre_name": 1, "ranking": 1}
+	if err := findAll(ctx, client, "movies", projection, &movies); err != nil {
+		return nil, err
+	}
+	for _, movie := range movies {
+		info := movieInfo{title: movie.Title, ranking: movie.Ranking.RankingValue, rankingName: movie.Ranking.RankingName}
+		for _, genre := range movie.Genre {
+			info.genres = append(info.genres, genre.GenreName)
+		}
+		c.movies[movie.ImdbID] = info
+	}
+
+	var users []struct {
+		UserID          string `bson:"user_id"`
+		FavouriteGenres []struct {
+			GenreName string `bson:"genre_name"`
+		} `bson:"favourite_genres"`
+	}
+	if err := findAll(ctx, client, "users", bson.M{"user_id": 1, "favourite_genres.genre_name": 1}, &users); err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		p := c.profile(user.UserID)
+		for _, genre := range user.FavouriteGenres {
+			p.favouriteGenres = append(p.favouriteGenres, genre.GenreName)
+		}
+	}
+
+	type saved struct {
+		UserID string `bson:"user_id"`
+		ImdbID string `bson:"imdb_id"`
+	}
+	for _, list := range []struct {
+		collection, kind string
+		weight           float64
+	}{
+		{"favourites", favourited, favouriteWeight},
+		{"watchlist", watchlisted, watchlistWeight},
+	} {
+		var items []saved
+		if err := findAll(ctx, client, list.collection, bson.M{"user_id": 1, "imdb_id": 1}, &items); err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			c.add(item.UserID, item.ImdbID, list.kind, list.weight)
+		}
+	}
+
+	var history []struct {
+		UserID    string `bson:"user_id"`
+		ImdbID    string `bson:"imdb_id"`
+		Completed bool   `bson:"completed"`
+	}
+	if err := findAll(ctx, client, "watch_history", bson.M{"user_id": 1, "imdb_id": 1, "completed": 1}, &history); err != nil {
+		return nil, err
+	}
+	for _, entry := range history {
+		kind, weight := started, startedWeight
+		if entry.Completed {
+			kind, weight = completed, completedWeight
+		}
+		c.add(entry.UserID, entry.ImdbID, kind, weight)
+	}
+
+	var ratings []struct {
+		UserID string `bson:"user_id"`
+		ImdbID string `bson:"imdb_id"`
+		Rating int    `bson:"rating"`
+	}
+	if err := findAll(ctx, client, "ratings", bson.M{"user_id": 1, "imdb_id": 1, "rating": 1}, &ratings); err != nil {
+		return nil, err
+	}
+	for _, rating := range ratings {
+		c.add(rating.UserID, rating.ImdbID, rated, float64(rating.Rating-3)*ratingWeight)
+	}
+	return c, nil
+}
+
+func findAll(ctx context.Context, client *mongo.Client, collection string, projection bson.M, results any) error {
+	opts := options.Find().SetProjection(projection)
+	cursor, err := database.OpenCollection(collection, client).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", collection, err)
+	}
+	if err := cursor.All(ctx, results); err != nil {
+		return fmt.Errorf("decoding %s: %w", collection, err)
+	}
+	return nil
+}
+
+// SeenIDs returns the movies a user saved, rated or watched, which are never
+// recommended to them
+func SeenIDs(ctx context.Context, client *mongo.Client, userID string) (map[string]bool, error) {
+	seen := map[string]bool{}
+	for _, collection := range []string{"favourites", "watchlist", "watch_history", "ratings"} {
+		var items []struct {
+			ImdbID string `bson:"imdb_id"`
+		}
+		opts := options.Find().SetProjection(bson.M{"imdb_id": 1, "_id": 0})
+		cursor, err := database.OpenCollection(collection, client).Find(ctx, bson.M{"user_id": userID}, opts)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", collection, err)
+		}
+		if err := cursor.All(ctx, &items); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", collection, err)
+		}
+		for _, item := range items {
+			seen[item.ImdbID] = true
+		}
+	}
+	return seen, nil
+}
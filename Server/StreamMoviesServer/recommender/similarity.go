@@ -0,0 +1,76 @@
+package recommender
+
+import (
+	"math"
+	"sort"
+)
+
+type neighbour struct {
+	imdbID     string
+	similarity float64
+}
+
+type pair struct{ a, b string }
+
+// itemSimilarities computes the cosine similarity of every two movies over
+// the users who liked them and keeps the k most similar neighbours of each.
+// Only positive weights count: disliking the same movie says little about
+// what two users enjoy. Users are capped at maxItems strongest movies so one
+// heavy user cannot make the pass quadratic in the catalogue size.
+func itemSimilarities(profiles map[string]*profile, k, maxItems int) map[string][]neighbour {
+	dots := map[pair]float64{}
+	norms := map[string]float64{}
+	for _, p := range profiles {
+		liked := strongest(p.weights, maxItems)
+		for i, a := range liked {
+			norms[a.imdbID] += a.similarity * a.similarity
+			for _, b := range liked[i+1:] {
+				key := pair{a.imdbID, b.imdbID}
+				if key.b < key.a {
+					key = pair{b.imdbID, a.imdbID}
+				}
+				dots[key] += a.similarity * b.similarity
+			}
+		}
+	}
+
+	neighbours := map[string][]neighbour{}
+	for key, dot := range dots {
+		similarity := dot / (math.Sqrt(norms[key.a]) * math.Sqrt(norms[key.b]))
+		neighbours[key.a] = append(neighbours[key.a], neighbour{key.b, similarity})
+		neighbours[key.b] = append(neighbours[key.b], neighbour{key.a, similarity})
+	}
+	for imdbID, list := range neighbours {
+		sortNeighbours(list)
+		if len(list) > k {
+			list = list[:k]
+		}
+		neighbours[imdbID] = list
+	}
+	return neighbours
+}
+
+// strongest returns the positively weighted movies, heaviest first, reusing
+// neighbour for the (movie, weight) pairs
+func strongest(weights map[string]float64, limit int) []neighbour {
+	liked := make([]neighbour, 0, len(weights))
+	for imdbID, weight := range weights {
+		if weight > 0 {
+			liked = append(liked, neighbour{imdbID, weight})
+		}
+	}
+	sortNeighbours(liked)
+	if len(liked) > limit {
+		liked = liked[:limit]
+	}
+	return liked
+}
+
+func sortNeighbours(list []neighbour) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].similarity != list[j].similarity {
+			return list[i].similarity > list[j].similarity
+		}
+		return list[i].imdbID < list[j].imdbID
+	})
+}
@@ -4,20 +4,80 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/controllers"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/graph"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/middlewares"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/ratelimit"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
-func SetupProtectedRoutes(router *gin.Engine, client *mongo.Client) {
+func SetupProtectedRoutes(router *gin.Engine, client *mongo.Client, limiter *ratelimit.Limiter, graphLimits graph.Limits) {
 	v1 := router.Group("/api/v1")
 	v1.Use(middlewares.AuthMiddleWare())
 
+	audited := func(action string, target middlewares.AuditTarget) gin.HandlerFunc {
+		return middlewares.Audit(client, action, target)
+	}
+	movieTarget := middlewares.AuditTarget{Collection: "movies", Key: "imdb_id", Param: "imdb_id"}
+	genreTarget := middlewares.AuditTarget{Collection: "genres", Key: "genre_id", Param: "id", Int: true}
+	personTarget := middlewares.AuditTarget{Collection: "people", Key: "person_id", Param: "id"}
+	rankingTarget := middlewares.AuditTarget{Collection: "rankings", Key: "ranking_value", Param: "value", Int: true}
+
 	v1.GET("/me", controllers.GetUser(client))
 	v1.PUT("/me", controllers.UpdateUser(client))
+	v1.GET("/me/watchlist", controllers.GetList(client, controllers.WatchlistCollection))
+	v1.POST("/me/watchlist/:imdb_id", controllers.AddToList(client, controllers.WatchlistCollection))
+	v1.DELETE("/me/watchlist/:imdb_id", controllers.RemoveFromList(client, controllers.WatchlistCollection))
+	v1.GET("/me/favourites", controllers.GetList(client, controllers.FavouritesCollection))
+	v1.POST("/me/favourites/:imdb_id", controllers.AddToList(client, controllers.FavouritesCollection))
+	v1.DELETE("/me/favourites/:imdb_id", controllers.RemoveFromList(client, controllers.FavouritesCollection))
+	v1.POST("/me/history", controllers.RecordWatchEvent(client))
+	v1.GET("/me/history", controllers.GetHistory(client))
+	v1.DELETE("/me/history", controllers.ClearHistory(client))
+	v1.DELETE("/me/history/:imdb_id", controllers.DeleteHistoryEntry(client))
+	v1.GET("/me/continue-watching", controllers.GetContinueWatching(client))
+	v1.GET("/me/ratings", controllers.GetRatings(client))
+	v1.PUT("/me/ratings/:imdb_id", controllers.RateMovie(client))
+	v1.DELETE("/me/ratings/:imdb_id", controllers.DeleteRating(client))
+
+	v1.GET("/events", controllers.StreamEvents())
 
-	v1.GET("/movie/:imdb_id", controllers.GetMovie(client))
-	v1.POST("/genre", controllers.AddOrUpdateGenre(client))
-	v1.POST("/addmovie", controllers.AddMovie(client))
+	// per user as it needs a token, revalidated every time so edits show at once
+	v1.GET("/movie/:imdb_id", middlewares.CacheControl("private, no-cache"), controllers.GetMovie(client))
+	v1.GET("/movie/:imdb_id/similar", controllers.GetSimilarMovies(client))
+	v1.POST("/movies/ask", middlewares.RateLimit(limiter, "ask"), controllers.AskMovies(client))
+	v1.POST("/genre", middlewares.AdminMiddleWare(), audited("genre.create", middlewares.AuditTarget{Collection: "genres", Key: "genre_id"}), controllers.AddGenre(client))
+	v1.PUT("/genre/:id", middlewares.AdminMiddleWare(), audited("genre.update", genreTarget), controllers.UpdateGenre(client))
+	v1.DELETE("/genre/:id", middlewares.AdminMiddleWare(), audited("genre.delete", genreTarget), controllers.DeleteGenre(client))
+	v1.POST("/rankings", middlewares.AdminMiddleWare(), audited("ranking.create", rankingTarget), controllers.AddRanking(client))
+	v1.PUT("/rankings/:value", middlewares.AdminMiddleWare(), audited("ranking.update", rankingTarget), controllers.UpdateRanking(client))
+	v1.DELETE("/rankings/:value", middlewares.AdminMiddleWare(), audited("ranking.delete", rankingTarget), controllers.DeleteRanking(client))
+	v1.POST("/addmovie", audited("movie.create", movieTarget), controllers.AddMovie(client))
 	v1.GET("/recommendedmovies", controllers.GetRecommendedMovies(client))
-	v1.PATCH("/movie/:imdb_id/updatereview", controllers.AdminReviewUpdate(client))
+	v1.PATCH("/movie/:imdb_id/updatereview", middlewares.RateLimit(limiter, "review"), audited("movie.review", movieTarget), controllers.AdminReviewUpdate(client))
+	v1.POST("/movie/:imdb_id/enrich", middlewares.AdminMiddleWare(), audited("movie.enrich", movieTarget), controllers.EnrichMovie(client))
+	v1.POST("/people", middlewares.AdminMiddleWare(), audited("person.create", personTarget), controllers.AddPerson(client))
+	v1.PUT("/people/:id", middlewares.AdminMiddleWare(), audited("person.update", personTarget), controllers.UpdatePerson(client))
+	v1.DELETE("/people/:id", middlewares.AdminMiddleWare(), audited("person.delete", personTarget), controllers.DeletePerson(client))
+
+	// mutations apply the rate limits and audit actions of their REST routes
+	graphQL := graph.Handler(client, limiter, graphLimits)
+	v1.GET("/graphql", middlewares.CacheControl("private, no-store"), graphQL)
+	v1.POST("/graphql", middlewares.CacheControl("private, no-store"), graphQL)
+
+	admin := v1.Group("/admin")
+	admin.Use(middlewares.AdminMiddleWare())
+	admin.GET("/export", controllers.ExportCatalogue(client))
+	admin.POST("/enrich", audited("catalogue.enrich", middlewares.AuditTarget{}), controllers.EnrichAllMovies(client))
+	admin.POST("/rerank", audited("catalogue.rerank", middlewares.AuditTarget{}), controllers.RerankMovies(client))
+	admin.PUT("/users/:user_id/role", audited("user.role", middlewares.AuditTarget{Collection: "users", Key: "user_id", Param: "user_id"}), controllers.UpdateUserRole(client))
+	admin.GET("/audit", controllers.GetAuditLog(client))
+
+	webhookTarget := middlewares.AuditTarget{Collection: "webhooks", Key: "webhook_id", Param: "id"}
+	admin.POST("/webhooks", audited("webhook.create", webhookTarget), controllers.AddWebhook(client))
+	admin.GET("/webhooks", controllers.GetWebhooks(client))
+	admin.GET("/webhooks/:id", controllers.GetWebhook(client))
+	admin.PUT("/webhooks/:id", audited("webhook.update", webhookTarget), controllers.UpdateWebhook(client))
+	admin.DELETE("/webhooks/:id", audited("webhook.delete", webhookTarget), controllers.DeleteWebhook(client))
+	admin.GET("/webhooks/:id/deliveries", controllers.GetWebhookDeliveries(client))
+	admin.POST("/webhooks/:id/deliveries/replay", audited("webhook.replay", webhookTarget), controllers.ReplayWebhookDeliveries(client))
 }
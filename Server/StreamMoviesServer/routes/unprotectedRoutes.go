@@ -3,19 +3,24 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/controllers"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/middlewares"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/ratelimit"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
-func SetupUnProtectedRoutes(router *gin.Engine, client *mongo.Client, mailChan chan models.MailData) {
+func SetupUnProtectedRoutes(router *gin.Engine, client *mongo.Client, mailChan chan models.MailData, limiter *ratelimit.Limiter) {
 	v1 := router.Group("/api/v1")
-	v1.GET("/movies", controllers.GetMovies(client))
-	v1.POST("/register", controllers.RegisterUser(client))
-	v1.POST("/login", controllers.LoginUser(client))
+	v1.GET("/movies", middlewares.CacheControl("public, max-age=30, must-revalidate"), controllers.GetMovies(client))
+	v1.POST("/register", middlewares.RateLimit(limiter, "register"), controllers.RegisterUser(client))
+	v1.POST("/login", middlewares.RateLimit(limiter, "login"), controllers.LoginUser(client))
 	v1.POST("/logout", controllers.LogoutHandler(client))
-	v1.GET("/genres", controllers.GetGenres(client))
+	v1.GET("/genres", middlewares.CacheControl("public, max-age=60, must-revalidate"), controllers.GetGenres(client))
+	v1.GET("/rankings", controllers.GetRankings(client))
+	v1.GET("/people", controllers.GetPeople(client))
+	v1.GET("/people/:id", controllers.GetPerson(client))
 	v1.POST("/refresh", controllers.RefreshTokenHandler(client))
-	v1.POST("/request-reset", controllers.RequestResetPassword(client, mailChan))
+	v1.POST("/request-reset", middlewares.RateLimit(limiter, "reset"), controllers.RequestResetPassword(client, mailChan))
 	v1.POST("/reset-password", controllers.ResetPassword(client))
 	v1.GET("/auth/google/login", controllers.GoogleLogin(client))
 	v1.GET("/auth/google/callback", controllers.GoogleCallback(client))
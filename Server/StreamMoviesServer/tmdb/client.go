@@ -0,0 +1,184 @@
+// Package tmdb contains a client for The Movie Database API and an enricher
+// that copies its metadata onto our movies
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultBaseURL = "https://api.themoviedb.org/3"
+
+var ErrNotFound = errors.New("not found on tmdb")
+
+// Client is the part of the TMDB API the enricher needs
+type Client interface {
+	FindByIMDbID(ctx context.Context, imdbID string) (*FindResult, error)
+	Movie(ctx context.Context, tmdbID int) (*Movie, error)
+}
+
+// fetcher returns the raw body of a TMDB request. key names the response
+// so it can be stored and replayed as a fixture.
+type fetcher interface {
+	fetch(ctx context.Context, key, path string, query url.Values) ([]byte, error)
+}
+
+type apiClient struct {
+	fetcher fetcher
+}
+
+func (a *apiClient) FindByIMDbID(ctx context.Context, imdbID string) (*FindResult, error) {
+	query := url.Values{"external_source": {"imdb_id"}}
+	data, err := a.fetcher.fetch(ctx, "find_"+imdbID, "/find/"+url.PathEscape(imdbID), query)
+	if err != nil {
+		return nil, err
+	}
+	var result FindResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("decoding tmdb find response: %w", err)
+	}
+	return &result, nil
+}
+
+func (a *apiClient) Movie(ctx context.Context, tmdbID int) (*Movie, error) {
+	id := strconv.Itoa(tmdbID)
+	query := url.Values{"append_to_response": {"credits,release_dates"}}
+	data, err := a.fetcher.fetch(ctx, "movie_"+id, "/movie/"+id, query)
+	if err != nil {
+		return nil, err
+	}
+	var movie Movie
+	if err := json.Unmarshal(data, &movie); err != nil {
+		return nil, fmt.Errorf("decoding tmdb movie response: %w", err)
+	}
+	return &movie, nil
+}
+
+// NewHTTPClient talks to the live API using a v4 read access token
+func NewHTTPClient(baseURL, bearerToken string) Client {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	return &apiClient{fetcher: &httpFetcher{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   bearerToken,
+		http:    &http.Client{Timeout: 15 * time.Second},
+	}}
+}
+
+// NewFixtureClient replays responses stored as <key>.json in fsys, so the
+// enricher can run offline
+func NewFixtureClient(fsys fs.FS) Client {
+	return &apiClient{fetcher: &fixtureFetcher{fsys: fsys}}
+}
+
+// NewRecordingClient behaves like NewHTTPClient and also saves every
+// response into dir in the layout NewFixtureClient expects
+func NewRecordingClient(baseURL, bearerToken, dir string) Client {
+	live := NewHTTPClient(baseURL, bearerToken).(*apiClient)
+	return &apiClient{fetcher: &recordingFetcher{next: live.fetcher, dir: dir}}
+}
+
+// NewClientFromEnv picks the client based on TMDB_MODE: "live" (default),
+// "record" or "fixture". Fixtures are read from TMDB_FIXTURE_DIR, falling
+// back to the ones bundled with this package.
+func NewClientFromEnv() (Client, error) {
+	mode := strings.ToLower(os.Getenv("TMDB_MODE"))
+	token := os.Getenv("TMDB_BEARER_TOKEN")
+	baseURL := os.Getenv("TMDB_BASE_URL")
+	fixtureDir := os.Getenv("TMDB_FIXTURE_DIR")
+
+	switch mode {
+	case "fixture":
+		if fixtureDir != "" {
+			return NewFixtureClient(os.DirFS(fixtureDir)), nil
+		}
+		return NewFixtureClient(bundledFixtures()), nil
+	case "record":
+		if token == "" || fixtureDir == "" {
+			return nil, errors.New("TMDB_BEARER_TOKEN and TMDB_FIXTURE_DIR are required to record fixtures")
+		}
+		return NewRecordingClient(baseURL, token, fixtureDir), nil
+	case "", "live":
+		if token == "" {
+			return nil, errors.New("could not get tmdb bearer token")
+		}
+		return NewHTTPClient(baseURL, token), nil
+	}
+	return nil, fmt.Errorf("unknown TMDB_MODE %q", mode)
+}
+
+type httpFetcher struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func (h *httpFetcher) fetch(ctx context.Context, _ string, path string, query url.Values) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+h.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("tmdb %s returned %s", path, resp.Status)
+	}
+	return body, nil
+}
+
+type fixtureFetcher struct {
+	fsys fs.FS
+}
+
+func (f *fixtureFetcher) fetch(_ context.Context, key, _ string, _ url.Values) ([]byte, error) {
+	data, err := fs.ReadFile(f.fsys, key+".json")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+type recordingFetcher struct {
+	next fetcher
+	dir  string
+}
+
+func (r *recordingFetcher) fetch(ctx context.Context, key, path string, query url.Values) ([]byte, error) {
+	data, err := r.next.fetch(ctx, key, path, query)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, key+".json"), data, 0o644); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,248 @@
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/catalogue"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	maxCast                     = 15
+	defaultCertificationCountry = "US"
+)
+
+var ErrMovieNotFound = errors.New("movie not found")
+
+// crewJobs are the crew credits worth keeping, the full list runs into hundreds
+var crewJobs = map[string]bool{
+	"Director":                true,
+	"Screenplay":              true,
+	"Writer":                  true,
+	"Novel":                   true,
+	"Producer":                true,
+	"Original Music Composer": true,
+	"Director of Photography": true,
+}
+
+// genreAliases maps lower case TMDB genre names to ours where they differ
+var genreAliases = map[string]string{
+	"science fiction": "sci-fi",
+}
+
+type Enricher struct {
+	Client Client
+	Mongo  *mongo.Client
+}
+
+type EnrichResult struct {
+	Movie          models.Movie `json:"movie"`
+	UnmappedGenres []string     `json:"unmapped_genres,omitempty"`
+}
+
+// Enrich looks the movie up on TMDB by its IMDb id, copies overview, release
+// date, runtime, languages, countries, certification and credits onto it,
+// links the credits to people and adds the TMDB genres we know about
+func (e *Enricher) Enrich(ctx context.Context, imdbID string) (*EnrichResult, error) {
+	movieCollection := database.OpenCollection("movies", e.Mongo)
+	var movie models.Movie
+	err := movieCollection.FindOne(ctx, bson.M{"imdb_id": imdbID}).Decode(&movie)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrMovieNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	genres, err := e.loadGenres(ctx)
+	if err != nil {
+		return nil, err
+	}
+	unmapped, err := e.apply(ctx, &movie, genres)
+	if err != nil {
+		return nil, err
+	}
+	if err := catalogue.PrepareMovie(ctx, e.Mongo, &movie); err != nil {
+		return nil, fmt.Errorf("linking people: %w", err)
+	}
+
+	update := database.Versioned(bson.M{"$set": bson.M{
+		"tmdb_id":           movie.TMDBID,
+		"overview":          movie.Overview,
+		"release_date":      movie.ReleaseDate,
+		"release_year":      movie.ReleaseYear,
+		"runtime":           movie.Runtime,
+		"original_language": movie.OriginalLanguage,
+		"spoken_languages":  movie.SpokenLanguages,
+		"countries":         movie.Countries,
+		"age_certification": movie.AgeCertification,
+		"cast":              movie.Cast,
+		"crew":              movie.Crew,
+		"genre":             movie.Genre,
+	}})
+	if _, err := movieCollection.UpdateOne(ctx, bson.M{"imdb_id": imdbID}, update); err != nil {
+		return nil, err
+	}
+	return &EnrichResult{Movie: movie, UnmappedGenres: unmapped}, nil
+}
+
+// apply looks movie up on TMDB, by its tmdb_id or else its IMDb id, and
+// copies the details onto it. It returns the TMDB genres with no counterpart
+// in genres, which are keyed by lower case name.
+func (e *Enricher) apply(ctx context.Context, movie *models.Movie, genres map[string]models.Genre) ([]string, error) {
+	tmdbID := movie.TMDBID
+	if tmdbID == 0 {
+		found, err := e.Client.FindByIMDbID(ctx, movie.ImdbID)
+		if err != nil {
+			return nil, err
+		}
+		if len(found.MovieResults) == 0 {
+			return nil, ErrNotFound
+		}
+		tmdbID = found.MovieResults[0].ID
+	}
+	details, err := e.Client.Movie(ctx, tmdbID)
+	if err != nil {
+		return nil, err
+	}
+	return applyDetails(movie, details, genres, certificationCountry()), nil
+}
+
+// EnrichAll enriches every movie, or only the ones never enriched before,
+// calling progress after each one. A failing movie does not stop the run.
+func (e *Enricher) EnrichAll(ctx context.Context, onlyMissing bool, progress func(imdbID string, err error)) (int, int, error) {
+	filter := bson.M{}
+	if onlyMissing {
+		filter["tmdb_id"] = bson.M{"$exists": false}
+	}
+	opts := options.Find().SetProjection(bson.M{"imdb_id": 1, "_id": 0})
+	cursor, err := database.OpenCollection("movies", e.Mongo).Find(ctx, filter, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	var ids []struct {
+		ImdbID string `bson:"imdb_id"`
+	}
+	if err := cursor.All(ctx, &ids); err != nil {
+		return 0, 0, err
+	}
+
+	enriched, failed := 0, 0
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return enriched, failed, err
+		}
+		_, err := e.Enrich(ctx, id.ImdbID)
+		if err != nil {
+			failed++
+		} else {
+			enriched++
+		}
+		if progress != nil {
+			progress(id.ImdbID, err)
+		}
+	}
+	return enriched, failed, nil
+}
+
+func (e *Enricher) loadGenres(ctx context.Context) (map[string]models.Genre, error) {
+	cursor, err := database.OpenCollection("genres", e.Mongo).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("loading genres: %w", err)
+	}
+	var genres []models.Genre
+	if err := cursor.All(ctx, &genres); err != nil {
+		return nil, fmt.Errorf("decoding genres: %w", err)
+	}
+	byName := make(map[string]models.Genre, len(genres))
+	for _, genre := range genres {
+		byName[strings.ToLower(genre.GenreName)] = genre
+	}
+	return byName, nil
+}
+
+// certificationCountry is the country whose age rating is stored, set
+// through TMDB_CERTIFICATION_COUNTRY
+func certificationCountry() string {
+	if country := os.Getenv("TMDB_CERTIFICATION_COUNTRY"); country != "" {
+		return strings.ToUpper(country)
+	}
+	return defaultCertificationCountry
+}
+
+// applyDetails copies TMDB metadata onto movie and returns the TMDB genres
+// that have no counterpart in our genres collection
+func applyDetails(movie *models.Movie, details *Movie, genres map[string]models.Genre, country string) []string {
+	movie.TMDBID = details.ID
+	movie.Overview = details.Overview
+	movie.ReleaseDate = details.ReleaseDate
+	movie.Runtime = details.Runtime
+	movie.OriginalLanguage = details.OriginalLanguage
+	movie.AgeCertification = details.Certification(country)
+
+	movie.SpokenLanguages = movie.SpokenLanguages[:0]
+	for _, language := range details.SpokenLanguages {
+		movie.SpokenLanguages = append(movie.SpokenLanguages, language.ISO6391)
+	}
+	movie.Countries = movie.Countries[:0]
+	for _, country := range details.ProductionCountries {
+		movie.Countries = append(movie.Countries, country.ISO31661)
+	}
+
+	movie.Cast = movie.Cast[:0]
+	for _, credit := range details.Credits.Cast {
+		if len(movie.Cast) == maxCast {
+			break
+		}
+		movie.Cast = append(movie.Cast, models.CastMember{
+			Name:        credit.Name,
+			Character:   credit.Character,
+			Order:       credit.Order,
+			ProfilePath: credit.ProfilePath,
+			TMDBID:      credit.ID,
+		})
+	}
+	movie.Crew = movie.Crew[:0]
+	for _, credit := range details.Credits.Crew {
+		if !crewJobs[credit.Job] {
+			continue
+		}
+		movie.Crew = append(movie.Crew, models.CrewMember{
+			Name:        credit.Name,
+			Job:         credit.Job,
+			Department:  credit.Department,
+			ProfilePath: credit.ProfilePath,
+			TMDBID:      credit.ID,
+		})
+	}
+
+	existing := make(map[int]bool, len(movie.Genre))
+	for _, genre := range movie.Genre {
+		existing[genre.GenreID] = true
+	}
+	var unmapped []string
+	for _, tmdbGenre := range details.Genres {
+		name := strings.ToLower(tmdbGenre.Name)
+		if alias, ok := genreAliases[name]; ok {
+			name = alias
+		}
+		genre, ok := genres[name]
+		if !ok {
+			unmapped = append(unmapped, tmdbGenre.Name)
+			continue
+		}
+		if !existing[genre.GenreID] {
+			movie.Genre = append(movie.Genre, genre)
+			existing[genre.GenreID] = true
+		}
+	}
+	return unmapped
+}
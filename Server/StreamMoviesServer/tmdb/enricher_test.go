@@ -0,0 +1,103 @@
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+)
+
+var testGenres = map[string]models.Genre{
+	"drama":  {GenreID: 1, GenreName: "Drama"},
+	"action": {GenreID: 2, GenreName: "Action"},
+	"sci-fi": {GenreID: 3, GenreName: "Sci-Fi"},
+}
+
+func genreNames(genres []models.Genre) []string {
+	names := make([]string, len(genres))
+	for i, genre := range genres {
+		names[i] = genre.GenreName
+	}
+	return names
+}
+
+func TestEnricherApply(t *testing.T) {
+	tests := []struct {
+		name         string
+		movie        models.Movie
+		wantTMDBID   int
+		wantGenres   []string
+		wantUnmapped []string
+	}{
+		{
+			name:         "unknown genres are reported",
+			movie:        models.Movie{ImdbID: "tt0111161"},
+			wantTMDBID:   278,
+			wantGenres:   []string{"Drama"},
+			wantUnmapped: []string{"Crime"},
+		},
+		{
+			name:       "aliases map to our genres",
+			movie:      models.Movie{ImdbID: "tt0133093"},
+			wantTMDBID: 603,
+			wantGenres: []string{"Action", "Sci-Fi"},
+		},
+		{
+			name:       "genres already on the movie are kept once",
+			movie:      models.Movie{ImdbID: "tt0133093", Genre: []models.Genre{testGenres["sci-fi"]}},
+			wantTMDBID: 603,
+			wantGenres: []string{"Sci-Fi", "Action"},
+		},
+		{
+			name:         "a known tmdb_id skips the IMDb lookup",
+			movie:        models.Movie{ImdbID: "tt9999999", TMDBID: 278},
+			wantTMDBID:   278,
+			wantGenres:   []string{"Drama"},
+			wantUnmapped: []string{"Crime"},
+		},
+	}
+	enricher := &Enricher{Client: NewFixtureClient(bundledFixtures())}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			movie := tt.movie
+			unmapped, err := enricher.apply(context.Background(), &movie, testGenres)
+			if err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			if movie.TMDBID != tt.wantTMDBID {
+				t.Errorf("tmdb_id = %d, want %d", movie.TMDBID, tt.wantTMDBID)
+			}
+			if got := genreNames(movie.Genre); !slices.Equal(got, tt.wantGenres) {
+				t.Errorf("genres = %v, want %v", got, tt.wantGenres)
+			}
+			if !slices.Equal(unmapped, tt.wantUnmapped) {
+				t.Errorf("unmapped = %v, want %v", unmapped, tt.wantUnmapped)
+			}
+		})
+	}
+}
+
+func TestEnricherApplyMissingImdbID(t *testing.T) {
+	fixtures := fstest.MapFS{
+		"find_tt0000002.json": {Data: []byte(`{"movie_results":[]}`)},
+	}
+	enricher := &Enricher{Client: NewFixtureClient(fixtures)}
+	for _, imdbID := range []string{
+		// no recorded response at all
+		"tt0000001",
+		// TMDB answers without a movie
+		"tt0000002",
+	} {
+		movie := models.Movie{ImdbID: imdbID}
+		_, err := enricher.apply(context.Background(), &movie, testGenres)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: err = %v, want ErrNotFound", imdbID, err)
+		}
+		if movie.TMDBID != 0 || len(movie.Genre) != 0 {
+			t.Errorf("%s: movie changed: %+v", imdbID, movie)
+		}
+	}
+}
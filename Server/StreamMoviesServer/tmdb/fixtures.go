@@ -0,0 +1,17 @@
+package tmdb
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed fixtures/*.json
+var fixtureFiles embed.FS
+
+func bundledFixtures() fs.FS {
+	sub, err := fs.Sub(fixtureFiles, "fixtures")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
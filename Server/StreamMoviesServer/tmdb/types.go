@@ -0,0 +1,107 @@
+package tmdb
+
+// FindResult is the response of /find/{external_id}
+type FindResult struct {
+	MovieResults []FindMovie `json:"movie_results"`
+}
+
+type FindMovie struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+type Genre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type CastCredit struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Character   string `json:"character"`
+	Order       int    `json:"order"`
+	ProfilePath string `json:"profile_path"`
+}
+
+type CrewCredit struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Job         string `json:"job"`
+	Department  string `json:"department"`
+	ProfilePath string `json:"profile_path"`
+}
+
+type Credits struct {
+	Cast []CastCredit `json:"cast"`
+	Crew []CrewCredit `json:"crew"`
+}
+
+type SpokenLanguage struct {
+	ISO6391     string `json:"iso_639_1"`
+	EnglishName string `json:"english_name"`
+}
+
+type ProductionCountry struct {
+	ISO31661 string `json:"iso_3166_1"`
+	Name     string `json:"name"`
+}
+
+// ReleaseDates lists the releases of a movie per country with their certification
+type ReleaseDates struct {
+	Results []CountryReleases `json:"results"`
+}
+
+type CountryReleases struct {
+	ISO31661     string    `json:"iso_3166_1"`
+	ReleaseDates []Release `json:"release_dates"`
+}
+
+type Release struct {
+	Certification string `json:"certification"`
+	ReleaseDate   string `json:"release_date"`
+	Type          int    `json:"type"`
+}
+
+// theatricalRelease is the release type TMDB uses for cinema releases
+const theatricalRelease = 3
+
+// Movie is /movie/{id} with credits and release dates appended
+type Movie struct {
+	ID                  int                 `json:"id"`
+	ImdbID              string              `json:"imdb_id"`
+	Title               string              `json:"title"`
+	Overview            string              `json:"overview"`
+	ReleaseDate         string              `json:"release_date"`
+	Runtime             int                 `json:"runtime"`
+	PosterPath          string              `json:"poster_path"`
+	OriginalLanguage    string              `json:"original_language"`
+	SpokenLanguages     []SpokenLanguage    `json:"spoken_languages"`
+	ProductionCountries []ProductionCountry `json:"production_countries"`
+	Genres              []Genre             `json:"genres"`
+	Credits             Credits             `json:"credits"`
+	ReleaseDates        ReleaseDates        `json:"release_dates"`
+}
+
+// Certification returns the age certification for a country, preferring
+// the theatrical release when several releases carry one
+func (m *Movie) Certification(country string) string {
+	for _, countryReleases := range m.ReleaseDates.Results {
+		if countryReleases.ISO31661 != country {
+			continue
+		}
+		fallback := ""
+		for _, release := range countryReleases.ReleaseDates {
+			if release.Certification == "" {
+				continue
+			}
+			if release.Type == theatricalRelease {
+				return release.Certification
+			}
+			if fallback == "" {
+				fallback = release.Certification
+			}
+		}
+		return fallback
+	}
+	return ""
+}
@@ -1,17 +1,25 @@
 package utils
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/logging"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/metrics"
 	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/models"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/tracing"
 	mail "github.com/xhit/go-simple-mail/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var logger = logging.For("utils")
+
 func ListenForMail(mailChan chan models.MailData) {
+	metrics.MailQueue(func() int { return len(mailChan) })
 	go func() {
 		for {
 			msg := <-mailChan
@@ -21,6 +29,9 @@ func ListenForMail(mailChan chan models.MailData) {
 }
 
 func sendMsg(m models.MailData) {
+	ctx, span := tracing.Start(tracing.FromCarrier(context.Background(), m.TraceContext), "mail.send",
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attribute.String("mail.template", m.Template)))
+
 	server := mail.NewSMTPClient()
 	server.Host = "localhost"
 	server.Port = 1025
@@ -30,27 +41,26 @@ func sendMsg(m models.MailData) {
 
 	client, err := server.Connect()
 	if err != nil {
-		log.Println(err)
+		logger.ErrorContext(ctx, "connecting to mail server failed", "error", err)
 	}
 	email := mail.NewMSG()
 	email.SetFrom(m.From).AddTo(m.To).SetSubject(m.Subject)
-	log.Println("Sending email to: ", m.To)
+	logger.InfoContext(ctx, "sending email", "to", m.To, "subject", m.Subject, "template", m.Template)
 	if m.Template == "" {
-		log.Println("from plain text", m.To)
 		email.SetBody(mail.TextHTML, m.Content)
 	} else {
-		log.Println("from template", m.To)
 		data, err := os.ReadFile(fmt.Sprintf("./templates/%s", m.Template))
 		if err != nil {
-			log.Println(err.Error())
+			logger.ErrorContext(ctx, "reading mail template failed", "template", m.Template, "error", err)
 		}
 		mailTemplate := string(data)
-		log.Println(mailTemplate)
 		msgToSend := strings.Replace(mailTemplate, "{{reset_link}}", m.Content, 3)
 		email.SetBody(mail.TextHTML, msgToSend)
 	}
 	err = email.Send(client)
 	if err != nil {
-		log.Println(err)
+		logger.ErrorContext(ctx, "sending email failed", "to", m.To, "error", err)
 	}
+	metrics.MailSent(err != nil)
+	tracing.End(span, err)
 }
@@ -0,0 +1,197 @@
+// Package validation holds the one validator request bodies, imports and
+// model replies go through. Besides the stock rules it knows:
+//
+//	imdb_id     an IMDb title id such as tt0111161
+//	youtube_id  an 11 character YouTube video id
+//	poster_url  an https URL on one of POSTER_HOSTS (image.tmdb.org by default)
+//	public_url  an http or https URL whose host is neither localhost nor a non-public IP address
+//	password    8 to 72 bytes with a lower case letter, an upper case letter and a digit
+//
+// Errors name fields by their JSON names. The validator also backs gin's
+// binding, so ShouldBindJSON and friends validate what they decode.
+package validation
+
+import (
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const defaultPosterHosts = "image.tmdb.org"
+
+var (
+	imdbIDPattern    = regexp.MustCompile(`^tt\d+$`)
+	youtubeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+)
+
+var (
+	once     sync.Once
+	instance *validator.Validate
+)
+
+func init() {
+	binding.Validator = ginValidator{}
+}
+
+// Validator returns the shared validator
+func Validator() *validator.Validate {
+	once.Do(func() {
+		instance = validator.New()
+		instance.RegisterTagNameFunc(jsonName)
+		for tag, rule := range map[string]validator.Func{
+			"imdb_id":    matches(imdbIDPattern),
+			"youtube_id": matches(youtubeIDPattern),
+			"poster_url": posterURL,
+			"public_url": publicURL,
+			"password":   password,
+		} {
+			if err := instance.RegisterValidation(tag, rule); err != nil {
+				panic(err)
+			}
+		}
+	})
+	return instance
+}
+
+// Struct validates the fields of a struct
+func Struct(value any) error {
+	return Validator().Struct(value)
+}
+
+// Var validates a single value, such as a path parameter, against tag
+func Var(value any, tag string) error {
+	return Validator().Var(value, tag)
+}
+
+// IsImdbID reports whether id is an IMDb title id
+func IsImdbID(id string) bool {
+	return imdbIDPattern.MatchString(id)
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+func matches(pattern *regexp.Regexp) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return pattern.MatchString(fl.Field().String())
+	}
+}
+
+func posterURL(fl validator.FieldLevel) bool {
+	parsed, err := url.Parse(fl.Field().String())
+	if err != nil || parsed.Scheme != "https" || parsed.User != nil {
+		return false
+	}
+	return slices.Contains(PosterHosts(), strings.ToLower(parsed.Hostname()))
+}
+
+// publicURL only looks at the host as written, names resolving to private
+// addresses have to be refused when connecting
+func publicURL(fl validator.FieldLevel) bool {
+	parsed, err := url.Parse(fl.Field().String())
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.User != nil {
+		return false
+	}
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return IsPublicAddr(addr)
+	}
+	return true
+}
+
+// sharedAddresses is the carrier-grade NAT range, private without being
+// reported so by netip
+var sharedAddresses = netip.MustParsePrefix("100.64.0.0/10")
+
+// IsPublicAddr reports whether addr is reachable on the internet, so not
+// loopback, private, link-local (cloud metadata services live there),
+// multicast or unspecified
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddresses.Contains(addr) &&
+		!(addr.Is4() && addr.As4()[0] == 0)
+}
+
+// PosterHosts are the hosts poster URLs may point to, from the comma
+// separated POSTER_HOSTS
+func PosterHosts() []string {
+	value := os.Getenv("POSTER_HOSTS")
+	if value == "" {
+		value = defaultPosterHosts
+	}
+	var hosts []string
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+func password(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	// bcrypt ignores everything past 72 bytes
+	if len(value) < 8 || len(value) > 72 {
+		return false
+	}
+	var lower, upper, digit bool
+	for _, r := range value {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return lower && upper && digit
+}
+
+// ginValidator lets gin bind through the shared validator
+type ginValidator struct{}
+
+func (ginValidator) ValidateStruct(obj any) error {
+	value := reflect.ValueOf(obj)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		return Struct(value.Interface())
+	case reflect.Slice, reflect.Array:
+		for i := range value.Len() {
+			if err := (ginValidator{}).ValidateStruct(value.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ginValidator) Engine() any {
+	return Validator()
+}
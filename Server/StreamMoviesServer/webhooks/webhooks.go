@@ -0,0 +1,266 @@
+// Package webhooks tells partner services about catalogue changes. Admins
+// subscribe URLs to event types, every matching event is queued as a
+// delivery per webhook and POSTed by the Worker, signed with the webhook's
+// secret and retried with a growing wait until it succeeds or runs out of
+// attempts. Failed deliveries stay in the log and can be replayed.
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/events"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/logging"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	Collection           = "webhooks"
+	DeliveriesCollection = "webhook_deliveries"
+	retentionIndex       = "webhook_deliveries_retention"
+	dueIndex             = "webhook_deliveries_due"
+
+	defaultRetentionDays = 30
+	// logSize is how many attempts a delivery remembers
+	logSize = 20
+)
+
+// Delivery statuses
+const (
+	Pending   = "pending"
+	Succeeded = "succeeded"
+	Failed    = "failed"
+)
+
+// Events are the event types webhooks can subscribe to. Events meant for a
+// single user's stream are sent without the user.
+var Events = []string{events.MovieCreated, events.MovieUpdated, events.RankingCompleted, events.GenreChanged, events.CatalogueReranked}
+
+var logger = logging.For("webhooks")
+
+// Webhook is a subscription of URL to event types. Its secret signs the
+// deliveries and is only shown when the webhook is created.
+type Webhook struct {
+	ID          bson.ObjectID `bson:"_id,omitempty" json:"-"`
+	WebhookID   string        `bson:"webhook_id" json:"webhook_id"`
+	URL         string        `bson:"url" json:"url"`
+	Events      []string      `bson:"events" json:"events"`
+	Description string        `bson:"description,omitempty" json:"description,omitempty"`
+	Active      bool          `bson:"active" json:"active"`
+	Secret      string        `bson:"secret" json:"secret,omitempty"`
+	CreatedAt   time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time     `bson:"updated_at" json:"updated_at"`
+}
+
+// Input is what admins send to create or change a webhook. A missing secret
+// is generated on create and kept on update, a missing active flag means
+// active.
+type Input struct {
+	URL         string   `json:"url" validate:"required,public_url,max=2000"`
+	Events      []string `json:"events" validate:"required,min=1,dive,oneof=movie.created movie.updated ranking.completed genre.changed catalogue.reranked"`
+	Description string   `json:"description" validate:"max=200"`
+	Active      *bool    `json:"active"`
+	Secret      string   `json:"secret" validate:"omitempty,min=16,max=200"`
+}
+
+func (in Input) active() bool {
+	return in.Active == nil || *in.Active
+}
+
+// Delivery is one event queued for one webhook. Payload is the body sent,
+// Attempts counts the tries since it was queued or last replayed and Log
+// keeps the latest of them.
+type Delivery struct {
+	ID            bson.ObjectID   `bson:"_id,omitempty" json:"-"`
+	DeliveryID    string          `bson:"delivery_id" json:"delivery_id"`
+	WebhookID     string          `bson:"webhook_id" json:"webhook_id"`
+	EventID       string          `bson:"event_id" json:"event_id"`
+	EventType     string          `bson:"event_type" json:"event_type"`
+	Payload       json.RawMessage `bson:"payload" json:"payload" swaggertype:"object"`
+	Status        string          `bson:"status" json:"status"`
+	Attempts      int             `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time       `bson:"next_attempt_at,omitzero" json:"next_attempt_at,omitzero"`
+	Log           []Attempt       `bson:"log" json:"log"`
+	CreatedAt     time.Time       `bson:"created_at" json:"created_at"`
+	DeliveredAt   time.Time       `bson:"delivered_at,omitzero" json:"delivered_at,omitzero"`
+}
+
+// Attempt is one POST of a delivery, with the response status or the error
+// that kept it from getting one
+type Attempt struct {
+	At         time.Time `bson:"at" json:"at"`
+	StatusCode int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMS int64     `bson:"duration_ms" json:"duration_ms"`
+}
+
+// EnsureIndexes indexes deliveries by when they are due and expires them
+// WEBHOOK_RETENTION_DAYS (30 by default, 0 keeps them) after they were queued
+func EnsureIndexes(ctx context.Context, client *mongo.Client) error {
+	days := defaultRetentionDays
+	if value := os.Getenv("WEBHOOK_RETENTION_DAYS"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 0 {
+			return fmt.Errorf("invalid WEBHOOK_RETENTION_DAYS %q", value)
+		}
+	}
+	_, err := database.OpenCollection(DeliveriesCollection, client).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+		Options: options.Index().SetName(dueIndex),
+	})
+	if err != nil {
+		return err
+	}
+	return database.EnsureTTLIndex(ctx, client, DeliveriesCollection, retentionIndex, "created_at", time.Duration(days)*24*time.Hour)
+}
+
+// Create stores a new webhook, returned with its secret
+func Create(ctx context.Context, client *mongo.Client, in Input) (Webhook, error) {
+	secret := in.Secret
+	if secret == "" {
+		var err error
+		if secret, err = newSecret(); err != nil {
+			return Webhook{}, err
+		}
+	}
+	now := time.Now()
+	webhook := Webhook{
+		WebhookID:   bson.NewObjectID().Hex(),
+		URL:         in.URL,
+		Events:      in.Events,
+		Description: in.Description,
+		Active:      in.active(),
+		Secret:      secret,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	_, err := database.OpenCollection(Collection, client).InsertOne(ctx, webhook)
+	return webhook, err
+}
+
+// newSecret is 32 random bytes, hex encoded
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// List returns a page of webhooks, oldest first, and how many there are
+func List(ctx context.Context, client *mongo.Client, skip, limit int64) ([]Webhook, int64, error) {
+	webhookCollection := database.OpenCollection(Collection, client)
+	total, err := webhookCollection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"secret": 0}).
+		SetSkip(skip).
+		SetLimit(limit)
+	cursor, err := webhookCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	webhooks := []Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, 0, err
+	}
+	return webhooks, total, nil
+}
+
+// Get returns the webhook with webhookID without its secret,
+// mongo.ErrNoDocuments when there is none
+func Get(ctx context.Context, client *mongo.Client, webhookID string) (Webhook, error) {
+	var webhook Webhook
+	opts := options.FindOne().SetProjection(bson.M{"secret": 0})
+	err := database.OpenCollection(Collection, client).FindOne(ctx, bson.M{"webhook_id": webhookID}, opts).Decode(&webhook)
+	return webhook, err
+}
+
+// Update replaces the settings of a webhook, returned without its secret.
+// The secret only changes when in has one.
+func Update(ctx context.Context, client *mongo.Client, webhookID string, in Input) (Webhook, error) {
+	set := bson.M{
+		"url":         in.URL,
+		"events":      in.Events,
+		"description": in.Description,
+		"active":      in.active(),
+		"updated_at":  time.Now(),
+	}
+	if in.Secret != "" {
+		set["secret"] = in.Secret
+	}
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"secret": 0})
+	var webhook Webhook
+	err := database.OpenCollection(Collection, client).
+		FindOneAndUpdate(ctx, bson.M{"webhook_id": webhookID}, bson.M{"$set": set}, opts).
+		Decode(&webhook)
+	return webhook, err
+}
+
+// Delete removes a webhook and its deliveries, reporting whether it existed
+func Delete(ctx context.Context, client *mongo.Client, webhookID string) (bool, error) {
+	result, err := database.OpenCollection(Collection, client).DeleteOne(ctx, bson.M{"webhook_id": webhookID})
+	if err != nil || result.DeletedCount == 0 {
+		return false, err
+	}
+	_, err = database.OpenCollection(DeliveriesCollection, client).DeleteMany(ctx, bson.M{"webhook_id": webhookID})
+	return true, err
+}
+
+// Deliveries returns a page of a webhook's deliveries, newest first, and how
+// many there are. An empty status matches all of them.
+func Deliveries(ctx context.Context, client *mongo.Client, webhookID, status string, skip, limit int64) ([]Delivery, int64, error) {
+	filter := bson.M{"webhook_id": webhookID}
+	if status != "" {
+		filter["status"] = status
+	}
+	deliveryCollection := database.OpenCollection(DeliveriesCollection, client)
+	total, err := deliveryCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+	cursor, err := deliveryCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	deliveries := []Delivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// Replay queues failed deliveries of a webhook again with a fresh set of
+// attempts: the ones in deliveryIDs, or all of them when it is empty. It
+// returns how many were queued.
+func Replay(ctx context.Context, client *mongo.Client, webhookID string, deliveryIDs []string) (int64, error) {
+	filter := bson.M{"webhook_id": webhookID, "status": Failed}
+	if len(deliveryIDs) > 0 {
+		filter["delivery_id"] = bson.M{"$in": deliveryIDs}
+	}
+	update := bson.M{"$set": bson.M{"status": Pending, "attempts": 0, "next_attempt_at": time.Now()}}
+	result, err := database.OpenCollection(DeliveriesCollection, client).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,343 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/database"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/events"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/metrics"
+	"github.com/nickhildpac/movie-stream-app/Server/StreamMoviesServer/validation"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	defaultTimeout     = 10 * time.Second
+	defaultMaxAttempts = 8
+
+	minRetryWait = 30 * time.Second
+	maxRetryWait = 6 * time.Hour
+
+	pollInterval = 5 * time.Second
+	concurrency  = 4
+	// a claimed delivery is due again this long after its timeout, so one
+	// lost with its instance is sent by another
+	leaseMargin = time.Minute
+
+	maxErrorLength = 500
+)
+
+// Headers sent with every delivery. The signature is "sha256=" and the hex
+// HMAC-SHA256, keyed with the webhook's secret, of the timestamp, a dot and
+// the body.
+const (
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+// Config bounds the deliveries: each POST gets Timeout to answer and a
+// delivery fails after MaxAttempts of them
+type Config struct {
+	Timeout     time.Duration
+	MaxAttempts int
+}
+
+// ConfigFromEnv reads WEBHOOK_TIMEOUT as a duration such as "10s" and
+// WEBHOOK_MAX_ATTEMPTS
+func ConfigFromEnv() (Config, error) {
+	config := Config{Timeout: defaultTimeout, MaxAttempts: defaultMaxAttempts}
+	if value := os.Getenv("WEBHOOK_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return config, fmt.Errorf("invalid WEBHOOK_TIMEOUT %q", value)
+		}
+		config.Timeout = timeout
+	}
+	if value := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts <= 0 {
+			return config, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS %q", value)
+		}
+		config.MaxAttempts = attempts
+	}
+	return config, nil
+}
+
+// Worker queues deliveries for published events and sends the due ones.
+// Deliveries are claimed in MongoDB, so workers of several instances share
+// the queue without sending anything twice.
+type Worker struct {
+	client *mongo.Client
+	config Config
+	http   *http.Client
+	wake   chan struct{}
+}
+
+func NewWorker(client *mongo.Client, config Config) *Worker {
+	// webhook URLs are checked when saved, but their names may resolve to
+	// another address by the time a delivery connects. No proxy, as the
+	// check would then see the proxy's address.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: config.Timeout, Control: refusePrivate}).DialContext
+	return &Worker{
+		client: client,
+		config: config,
+		http: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+			// a redirect is an answer the receiver has to fix, following
+			// it would turn the POST into a GET
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// refusePrivate keeps deliveries from reaching the instance's own network,
+// such as the cloud metadata service, whatever the URL's name resolved to
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !validation.IsPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("refusing to deliver to non-public address %s", addrPort.Addr())
+	}
+	return nil
+}
+
+// Enqueue queues event for every active webhook subscribed to its type. It
+// is registered on the domain bus, so it runs once per event.
+func (w *Worker) Enqueue(ctx context.Context, event events.Event) error {
+	cursor, err := database.OpenCollection(Collection, w.client).Find(ctx,
+		bson.M{"active": true, "events": event.Type},
+		options.Find().SetProjection(bson.M{"webhook_id": 1}))
+	if err != nil {
+		return err
+	}
+	var subscribed []Webhook
+	if err := cursor.All(ctx, &subscribed); err != nil {
+		return err
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	deliveries := make([]Delivery, len(subscribed))
+	for i, webhook := range subscribed {
+		deliveries[i] = Delivery{
+			DeliveryID:    bson.NewObjectID().Hex(),
+			WebhookID:     webhook.WebhookID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       payload,
+			Status:        Pending,
+			NextAttemptAt: now,
+			Log:           []Attempt{},
+			CreatedAt:     now,
+		}
+	}
+	if _, err := database.OpenCollection(DeliveriesCollection, w.client).InsertMany(ctx, deliveries); err != nil {
+		return err
+	}
+	w.Wake()
+	return nil
+}
+
+// Wake has the worker look for due deliveries now rather than at its next poll
+func (w *Worker) Wake() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start sends due deliveries until ctx is done
+func (w *Worker) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Go(func() { w.run(ctx) })
+	}
+	wg.Wait()
+}
+
+func (w *Worker) run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		for ctx.Err() == nil {
+			delivery, err := w.claim(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.ErrorContext(ctx, "claiming webhook delivery failed", "error", err)
+				}
+				break
+			}
+			if delivery == nil {
+				break
+			}
+			w.deliver(ctx, *delivery)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// claim takes the delivery that has been due longest by pushing its next
+// attempt past the end of this one, nil when none is due
+func (w *Worker) claim(ctx context.Context) (*Delivery, error) {
+	now := time.Now()
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+	var delivery Delivery
+	err := database.OpenCollection(DeliveriesCollection, w.client).FindOneAndUpdate(ctx,
+		bson.M{"status": Pending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"next_attempt_at": now.Add(w.config.Timeout + leaseMargin)}},
+		opts).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// deliver makes one attempt at a claimed delivery and records how it went
+func (w *Worker) deliver(ctx context.Context, delivery Delivery) {
+	var webhook Webhook
+	err := database.OpenCollection(Collection, w.client).FindOne(ctx, bson.M{"webhook_id": delivery.WebhookID}).Decode(&webhook)
+	var attempt Attempt
+	switch {
+	case err == mongo.ErrNoDocuments:
+		attempt = Attempt{At: time.Now(), Error: "webhook no longer exists"}
+	case err != nil:
+		logger.ErrorContext(ctx, "loading webhook failed", "webhook_id", delivery.WebhookID, "error", err)
+		return
+	case !webhook.Active:
+		attempt = Attempt{At: time.Now(), Error: "webhook is disabled"}
+	default:
+		attempt = w.send(ctx, webhook, delivery)
+	}
+
+	delivered := attempt.Error == "" && attempt.StatusCode >= 200 && attempt.StatusCode < 300
+	attempts := delivery.Attempts + 1
+	set := bson.M{"attempts": attempts}
+	update := bson.M{
+		"$set":  set,
+		"$push": bson.M{"log": bson.M{"$each": []Attempt{attempt}, "$slice": -logSize}},
+	}
+	switch {
+	case delivered:
+		set["status"] = Succeeded
+		set["delivered_at"] = time.Now()
+		update["$unset"] = bson.M{"next_attempt_at": ""}
+	case attempts >= w.config.MaxAttempts || !webhook.Active:
+		set["status"] = Failed
+		update["$unset"] = bson.M{"next_attempt_at": ""}
+		metrics.WebhookFailed(delivery.EventType)
+		logger.WarnContext(ctx, "webhook delivery failed", "webhook_id", delivery.WebhookID, "delivery_id", delivery.DeliveryID, "attempts", attempts, "status", attempt.StatusCode, "error", attempt.Error)
+	default:
+		set["next_attempt_at"] = time.Now().Add(retryWait(attempts))
+	}
+	// the attempt happened, record it even when ctx ended meanwhile
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	_, err = database.OpenCollection(DeliveriesCollection, w.client).UpdateOne(recordCtx, bson.M{"delivery_id": delivery.DeliveryID}, update)
+	if err != nil {
+		logger.ErrorContext(ctx, "recording webhook delivery failed", "delivery_id", delivery.DeliveryID, "error", err)
+	}
+}
+
+// send POSTs the delivery's payload to the webhook
+func (w *Worker) send(ctx context.Context, webhook Webhook, delivery Delivery) Attempt {
+	started := time.Now()
+	attempt := Attempt{At: started}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = truncate(err.Error())
+		return attempt
+	}
+	timestamp := started.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MovieStream-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.DeliveryID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := w.http.Do(req)
+	attempt.DurationMS = time.Since(started).Milliseconds()
+	outcome := metrics.Failure
+	if err != nil {
+		attempt.Error = truncate(err.Error())
+	} else {
+		// drained so the connection can be reused
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		attempt.StatusCode = resp.StatusCode
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			outcome = metrics.Success
+		}
+	}
+	metrics.WebhookAttempt(delivery.EventType, outcome, time.Since(started))
+	return attempt
+}
+
+// Sign is the signature header value of body sent at timestamp, receivers
+// compute the same and compare in constant time
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryWait doubles from minRetryWait with each failed attempt, up to
+// maxRetryWait
+func retryWait(attempts int) time.Duration {
+	wait := minRetryWait
+	for i := 1; i < attempts && wait < maxRetryWait; i++ {
+		wait *= 2
+	}
+	return min(wait, maxRetryWait)
+}
+
+func truncate(message string) string {
+	if len(message) > maxErrorLength {
+		return message[:maxErrorLength]
+	}
+	return message
+}
@@ -0,0 +1,59 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"movie.created","data":{"imdb_id":"tt0111161"}}`)
+	// printf '%s' "1700000000.$body" | openssl dgst -sha256 -hmac whsec_test
+	want := "sha256=b5c055afe8ecab98bbb718932dc0396893e6b021c4074a9fce4bfa15522098bf"
+	if got := Sign("whsec_test", 1700000000, body); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if Sign("whsec_test", 1700000001, body) == want {
+		t.Error("the timestamp is not signed")
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{10, 256 * time.Minute},
+		// 512 minutes is past the cap
+		{11, 6 * time.Hour},
+		{1000, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryWait(tt.attempts); got != tt.want {
+			t.Errorf("retryWait(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRefusePrivate(t *testing.T) {
+	tests := []struct {
+		address string
+		refused bool
+	}{
+		{"93.184.215.14:443", false},
+		{"[2606:4700:4700::1111]:443", false},
+		{"127.0.0.1:80", true},
+		{"169.254.169.254:80", true},
+		{"10.1.2.3:8080", true},
+		{"[::1]:80", true},
+		{"[::ffff:192.168.0.1]:80", true},
+	}
+	for _, tt := range tests {
+		err := refusePrivate("tcp", tt.address, nil)
+		if refused := err != nil; refused != tt.refused {
+			t.Errorf("refusePrivate(%s) = %v, want refused %v", tt.address, err, tt.refused)
+		}
+	}
+}